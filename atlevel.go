@@ -0,0 +1,94 @@
+package logger
+
+import "log/slog"
+
+// leveledValue marks an attribute value emitted only when the logger is
+// enabled for level. It resolves to the wrapped value so that it degrades
+// gracefully when logged through a handler that does not know about it.
+type leveledValue struct {
+	level slog.Level
+	value slog.Value
+}
+
+func (v leveledValue) LogValue() slog.Value { return v.value }
+
+// AtLevel marks attr as emitted only when the logger is enabled for records
+// at level. For example, AtLevel(slog.LevelDebug, slog.Any("payload", p))
+// attaches the payload to records of any level while the logger runs at
+// debug level, and drops it otherwise. This keeps verbose detail available
+// on demand without cluttering production logs.
+func AtLevel(level slog.Level, attr slog.Attr) slog.Attr {
+	return slog.Attr{Key: attr.Key, Value: slog.AnyValue(leveledValue{level: level, value: attr.Value})}
+}
+
+// filterLeveled unwraps the attributes marked with AtLevel that are enabled
+// at minLevel and drops the others. It reports whether any attribute was
+// marked, so callers can skip rebuilding records that have none.
+func filterLeveled(attrs []slog.Attr, minLevel slog.Level) ([]slog.Attr, bool) {
+	var out []slog.Attr
+	marked := false
+	for i, a := range attrs {
+		fa, keep, m := filterLeveledAttr(a, minLevel)
+		if m && !marked {
+			marked = true
+			out = append(make([]slog.Attr, 0, len(attrs)), attrs[:i]...)
+		}
+		if marked && keep {
+			out = append(out, fa)
+		}
+	}
+	if !marked {
+		return attrs, false
+	}
+	return out, true
+}
+
+func filterLeveledAttr(a slog.Attr, minLevel slog.Level) (slog.Attr, bool, bool) {
+	switch a.Value.Kind() {
+	case slog.KindLogValuer:
+		lv, ok := a.Value.LogValuer().(leveledValue)
+		if !ok {
+			return a, true, false
+		}
+		if lv.level < minLevel {
+			return a, false, true
+		}
+		a.Value = lv.value
+		if a.Value.Kind() == slog.KindGroup {
+			inner, _, _ := filterLeveledAttr(a, minLevel)
+			return inner, true, true
+		}
+		return a, true, true
+	case slog.KindGroup:
+		group, marked := filterLeveled(a.Value.Group(), minLevel)
+		if !marked {
+			return a, true, false
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(group...)}, true, true
+	default:
+		return a, true, false
+	}
+}
+
+// filterLeveledRecord applies filterLeveled to the attributes of record.
+func filterLeveledRecord(record slog.Record, minLevel slog.Level) slog.Record {
+	marked := false
+	record.Attrs(func(a slog.Attr) bool {
+		_, _, marked = filterLeveledAttr(a, minLevel)
+		return !marked
+	})
+	if !marked {
+		return record
+	}
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs, _ = filterLeveled(attrs, minLevel)
+
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(attrs...)
+	return out
+}
@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAzureEndpoint is the global ingestion endpoint, used when the
+	// connection string names none.
+	defaultAzureEndpoint = "https://dc.services.visualstudio.com"
+	azureTrackPath       = "/v2.1/track"
+)
+
+// Application Insights severity levels.
+const (
+	azureVerbose     = 0
+	azureInformation = 1
+	azureWarning     = 2
+	azureError       = 3
+	azureCritical    = 4
+)
+
+// azureHandler is a slog.Handler that sends records to Azure Monitor
+// Application Insights through its ingestion API: records at error level
+// and above as exceptions, the others as traces. Attributes become custom
+// properties, groups being flattened into dotted keys. A background
+// goroutine sends the telemetry in batches.
+type azureHandler struct {
+	level     slog.Leveler
+	addSource bool
+	goas      []groupOrAttrs // added with WithGroup and WithAttrs, in order
+	iKey      string
+	client    *azureClient
+}
+
+// newAzureHandler returns a handler sending to the Application Insights
+// resource of the connection string.
+func newAzureHandler(config Config, opts *slog.HandlerOptions) (*azureHandler, error) {
+	iKey, endpoint, err := parseAzureConnectionString(config.AzureConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, batchWait := batchSettings(config, config.AzureBatchSize, config.AzureBatchWait)
+	return &azureHandler{
+		level:     opts.Level,
+		addSource: opts.AddSource,
+		iKey:      iKey,
+		client:    newAzureClient(endpoint+azureTrackPath, batchSize, batchWait),
+	}, nil
+}
+
+// parseAzureConnectionString returns the instrumentation key and the
+// ingestion endpoint of an Application Insights connection string, such as
+// "InstrumentationKey=...;IngestionEndpoint=https://...".
+func parseAzureConnectionString(s string) (iKey, endpoint string, err error) {
+	endpoint = defaultAzureEndpoint
+	for _, part := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(k) {
+		case "instrumentationkey":
+			iKey = v
+		case "ingestionendpoint":
+			endpoint = strings.TrimSuffix(v, "/")
+		}
+	}
+	if iKey == "" {
+		return "", "", errors.New("azure connection string has no InstrumentationKey")
+	}
+	return iKey, endpoint, nil
+}
+
+// Enabled reports whether the level is at or above the configured minimum.
+func (h *azureHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.level != nil {
+		minLevel = h.level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle converts the record to a telemetry envelope and queues it for the
+// next batch.
+func (h *azureHandler) Handle(_ context.Context, record slog.Record) error {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	var recordErr error
+	record.Attrs(func(a slog.Attr) bool {
+		if err, ok := a.Value.Resolve().Any().(error); ok && recordErr == nil {
+			recordErr = err
+		}
+		attrs = append(attrs, a)
+		return true
+	})
+
+	properties := map[string]string{}
+	for _, a := range nestAttrs(h.goas, attrs) {
+		eachFlatAttr("", a, func(key string, v any) {
+			properties[key] = fmt.Sprint(v)
+		})
+	}
+	if h.addSource && record.PC != 0 {
+		if src := recordSource(record); src != nil {
+			properties[slog.SourceKey] = fmt.Sprintf("%s:%d", src.File, src.Line)
+		}
+	}
+
+	severity := azureSeverity(record.Level)
+	var data azureData
+	if record.Level >= slog.LevelError {
+		exception := azureException{TypeName: "error", Message: record.Message}
+		if recordErr != nil {
+			exception.TypeName = reflect.TypeOf(recordErr).String()
+			exception.Message = record.Message + ": " + recordErr.Error()
+		}
+		properties[slog.MessageKey] = record.Message
+		data = azureData{
+			BaseType: "ExceptionData",
+			BaseData: azureExceptionData{
+				Ver:           2,
+				Exceptions:    []azureException{exception},
+				SeverityLevel: severity,
+				Properties:    properties,
+			},
+		}
+	} else {
+		data = azureData{
+			BaseType: "MessageData",
+			BaseData: azureMessageData{
+				Ver:           2,
+				Message:       record.Message,
+				SeverityLevel: severity,
+				Properties:    properties,
+			},
+		}
+	}
+
+	name := "Microsoft.ApplicationInsights.Message"
+	if data.BaseType == "ExceptionData" {
+		name = "Microsoft.ApplicationInsights.Exception"
+	}
+	t := record.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	h.client.enqueue(azureEnvelope{
+		Name: name,
+		Time: t.UTC().Format(time.RFC3339Nano),
+		IKey: h.iKey,
+		Data: data,
+	})
+	return nil
+}
+
+// WithAttrs returns a new handler with the given attributes.
+func (h *azureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{attrs: attrs})
+	return &h2
+}
+
+// WithGroup returns a new handler with the given group name.
+func (h *azureHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{group: name})
+	return &h2
+}
+
+// Close sends the queued telemetry and stops the background goroutine.
+func (h *azureHandler) Close() error {
+	return h.client.Close()
+}
+
+// azureSeverity maps a slog level to an Application Insights severity level.
+func azureSeverity(level slog.Level) int {
+	switch {
+	case level >= LevelFatal:
+		return azureCritical
+	case level >= slog.LevelError:
+		return azureError
+	case level >= slog.LevelWarn:
+		return azureWarning
+	case level >= slog.LevelInfo:
+		return azureInformation
+	default:
+		return azureVerbose
+	}
+}
+
+// azureEnvelope is a telemetry item of the Application Insights ingestion
+// API.
+type azureEnvelope struct {
+	Name string    `json:"name"`
+	Time string    `json:"time"`
+	IKey string    `json:"iKey"`
+	Data azureData `json:"data"`
+}
+
+type azureData struct {
+	BaseType string `json:"baseType"`
+	BaseData any    `json:"baseData"`
+}
+
+type azureMessageData struct {
+	Ver           int               `json:"ver"`
+	Message       string            `json:"message"`
+	SeverityLevel int               `json:"severityLevel"`
+	Properties    map[string]string `json:"properties,omitempty"`
+}
+
+type azureExceptionData struct {
+	Ver           int               `json:"ver"`
+	Exceptions    []azureException  `json:"exceptions"`
+	SeverityLevel int               `json:"severityLevel"`
+	Properties    map[string]string `json:"properties,omitempty"`
+}
+
+type azureException struct {
+	TypeName string `json:"typeName"`
+	Message  string `json:"message"`
+}
+
+// azureClient batches envelopes and sends them to Application Insights in
+// the background.
+type azureClient struct {
+	*batcher[azureEnvelope]
+	url  string
+	http *http.Client
+}
+
+func newAzureClient(url string, batchSize int, batchWait time.Duration) *azureClient {
+	c := &azureClient{
+		url:  url,
+		http: &http.Client{Timeout: 10 * time.Second},
+	}
+	c.batcher = newBatcher(batchSize, batchWait, c.push)
+	return c
+}
+
+// push sends a batch, retrying as postBatch does. A 206 response, partial
+// success, is not retried, since the accepted items would be sent twice.
+func (c *azureClient) push(batch []azureEnvelope) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: encoding Application Insights batch: %v\n", err)
+		return
+	}
+	if err := postBatch(c.http, c.url, "application insights", body); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: dropping %d records after failed Application Insights push: %v\n", len(batch), err)
+	}
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultBatchWait = time.Second
+	// batchMaxAttempts is the number of times a batch is sent before it is
+	// dropped.
+	batchMaxAttempts = 3
+	// batchQueueFactor is the number of batches of items a batcher queues.
+	batchQueueFactor = 10
+)
+
+// batchSettings returns the batch size and wait of a network sink: its own
+// settings if set, or else the shared BatchSize and BatchWait, or else the
+// defaults.
+func batchSettings(config Config, size int, wait time.Duration) (int, time.Duration) {
+	if size <= 0 {
+		size = config.BatchSize
+	}
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if wait <= 0 {
+		wait = config.BatchWait
+	}
+	if wait <= 0 {
+		wait = defaultBatchWait
+	}
+	return size, wait
+}
+
+// batcher queues the items of a network sink and passes them to flush in
+// batches from a background goroutine, once size items are queued or the
+// oldest has waited about wait, whichever comes first. flush must not keep
+// the batch, which is reused.
+type batcher[T any] struct {
+	size  int
+	wait  time.Duration
+	flush func(batch []T)
+
+	items chan T
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newBatcher[T any](size int, wait time.Duration, flush func(batch []T)) *batcher[T] {
+	b := &batcher[T]{
+		size:  size,
+		wait:  wait,
+		flush: flush,
+		items: make(chan T, size*batchQueueFactor),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// enqueue queues an item without blocking. Items are dropped when the queue
+// is full, so a slow or unreachable endpoint never stalls logging.
+func (b *batcher[T]) enqueue(item T) {
+	select {
+	case b.items <- item:
+	default:
+	}
+}
+
+// Close flushes the queued items and waits for the last flush to finish.
+func (b *batcher[T]) Close() error {
+	b.once.Do(func() {
+		close(b.stop)
+		<-b.done
+	})
+	return nil
+}
+
+func (b *batcher[T]) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.wait)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, b.size)
+	push := func() {
+		if len(batch) > 0 {
+			b.flush(batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case item := <-b.items:
+			batch = append(batch, item)
+			if len(batch) >= b.size {
+				push()
+			}
+		case <-ticker.C:
+			push()
+		case <-b.stop:
+			for {
+				select {
+				case item := <-b.items:
+					batch = append(batch, item)
+					if len(batch) >= b.size {
+						push()
+					}
+				default:
+					push()
+					return
+				}
+			}
+		}
+	}
+}
+
+// postBatch posts a JSON batch to url, retrying with backoff on network
+// errors, request timeouts, rate limiting and server errors. name names the
+// endpoint in errors.
+func postBatch(client *http.Client, url, name string, body []byte) error {
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		retry, err := sendBatch(client, url, name, body)
+		if err == nil || !retry || attempt == batchMaxAttempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// sendBatch performs a single post. It reports whether a failure is
+// retryable.
+func sendBatch(client *http.Client, url, name string, body []byte) (bool, error) {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500:
+		return true, fmt.Errorf("%s returned %s", name, resp.Status)
+	default:
+		return false, fmt.Errorf("%s returned %s", name, resp.Status)
+	}
+}
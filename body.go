@@ -0,0 +1,342 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// defaultBodyMaxSize is the number of bytes of a body logged by default.
+	defaultBodyMaxSize = 4 << 10
+	// bodyCaptureLimit is the number of bytes of a body LogBodies keeps for
+	// redaction, which must see structured bodies whole.
+	bodyCaptureLimit = 64 << 10
+)
+
+// defaultBodyContentTypes are the media types of the bodies logged by
+// default: text, JSON, XML and forms.
+var defaultBodyContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/xml",
+	"application/*+xml",
+	"application/x-www-form-urlencoded",
+	"application/graphql",
+}
+
+// BodyLogOptions bounds the bodies logged by Body and LogBodies.
+type BodyLogOptions struct {
+	// MaxSize is the number of bytes of a body logged; longer bodies are
+	// truncated. Defaults to 4 KiB.
+	MaxSize int
+	// ContentTypes lists the media types of bodies that are logged, such as
+	// "application/json", or "text/*" for a whole type. Bodies of other
+	// types are skipped. Defaults to text, JSON, XML and form types.
+	ContentTypes []string
+	// Level is the level of the records logged by LogBodies. Defaults to
+	// info.
+	Level slog.Level
+}
+
+// Body returns an attribute describing an HTTP or gRPC body of the given
+// content type: a group of its "content_type", its "size" in bytes and
+// either its "body", as a string truncated to MaxSize bytes with
+// "truncated" set to true, or "skipped" with the reason the body was not
+// logged.
+//
+// The body is redacted before it is truncated. JSON bodies and forms are
+// decoded so that RedactKeys and RedactPaths apply to their fields, paths
+// starting with the attribute key, such as "request_body.password".
+func Body(key string, body []byte, contentType string, opts BodyLogOptions) slog.Attr {
+	return slog.Any(key, newBodyValue(body, len(body), contentType, opts))
+}
+
+// bodyValue is a body to be logged, resolved to a group. The redactor
+// redacts it as a whole before it is resolved.
+type bodyValue struct {
+	data        []byte // body, possibly only its first bytes
+	size        int    // size of the whole body
+	partial     bool   // data holds only the first bytes of the body
+	contentType string
+	maxSize     int
+	skipped     string // reason the body is not logged, if any
+	redacted    bool   // data was redacted and must be withheld
+}
+
+// newBodyValue returns the bodyValue of data, the first bytes of a body of
+// the given size.
+func newBodyValue(data []byte, size int, contentType string, opts BodyLogOptions) *bodyValue {
+	b := &bodyValue{
+		data:        data,
+		size:        size,
+		partial:     size > len(data),
+		contentType: contentType,
+		maxSize:     opts.MaxSize,
+	}
+	if b.maxSize <= 0 {
+		b.maxSize = defaultBodyMaxSize
+	}
+	types := opts.ContentTypes
+	if len(types) == 0 {
+		types = defaultBodyContentTypes
+	}
+	if !bodyLoggable(contentType, data, types) {
+		b.skipped = "content type"
+	}
+	return b
+}
+
+// bodyLoggable reports whether a body of the content type is logged.
+// Bodies without a content type are logged if they are valid UTF-8 text.
+func bodyLoggable(contentType string, data []byte, types []string) bool {
+	if contentType == "" {
+		return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if bodyTypeMatches(strings.ToLower(t), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyTypeMatches reports whether the media type matches pattern, in which
+// "*" matches a whole type, subtype or subtype prefix: "text/*" matches
+// "text/plain" and "application/*+json" matches "application/ld+json".
+func bodyTypeMatches(pattern, mediaType string) bool {
+	pType, pSub, _ := strings.Cut(pattern, "/")
+	mType, mSub, _ := strings.Cut(mediaType, "/")
+	if pType != "*" && pType != mType {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(pSub, "*"); ok {
+		return strings.HasSuffix(mSub, suffix)
+	}
+	return pSub == mSub
+}
+
+// mediaType returns the media type of the body, or "" if it has none.
+func (b *bodyValue) mediaType() string {
+	mediaType, _, _ := mime.ParseMediaType(b.contentType)
+	return mediaType
+}
+
+// LogValue resolves the body to a group.
+func (b *bodyValue) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 4)
+	if b.contentType != "" {
+		attrs = append(attrs, slog.String("content_type", b.contentType))
+	}
+	attrs = append(attrs, slog.Int("size", b.size))
+	switch {
+	case b.skipped != "":
+		return slog.GroupValue(append(attrs, slog.String("skipped", b.skipped))...)
+	case b.redacted:
+		return slog.GroupValue(append(attrs, slog.String("body", redactedValue))...)
+	}
+
+	data, truncated := b.data, b.partial
+	if len(data) > b.maxSize {
+		data, truncated = data[:b.maxSize], true
+		for len(data) > 0 && !utf8.RuneStart(b.data[len(data)]) {
+			data = data[:len(data)-1]
+		}
+	}
+	attrs = append(attrs, slog.String("body", string(data)))
+	if truncated {
+		attrs = append(attrs, slog.Bool("truncated", true))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// redactBody returns a copy of the body with its fields redacted by the
+// paths and keys of r, and the patterns and PII detectors masked. A JSON
+// body or form that cannot be decoded, because it is invalid or was only
+// partly captured, is withheld if fields may have to be redacted.
+func (r *redactor) redactBody(paths [][]string, b *bodyValue, depth int) *bodyValue {
+	if b.skipped != "" {
+		return b
+	}
+	out := *b
+	fields := len(paths) > 0 || len(r.keys) > 0
+	switch mediaType := b.mediaType(); {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var decoded any
+		if b.partial || json.Unmarshal(b.data, &decoded) != nil {
+			if fields {
+				out.redacted = true
+				return &out
+			}
+			break
+		}
+		if encoded, err := json.Marshal(r.value(paths, decoded, depth)); err == nil {
+			out.data = encoded
+		}
+		return &out
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(b.data))
+		if b.partial || err != nil {
+			if fields {
+				out.redacted = true
+				return &out
+			}
+			break
+		}
+		decoded := make(map[string]any, len(form))
+		for k, vs := range form {
+			values := make([]any, len(vs))
+			for i, v := range vs {
+				values[i] = v
+			}
+			decoded[k] = values
+		}
+		out.data = encodeForm(r.value(paths, decoded, depth).(map[string]any))
+		return &out
+	}
+	out.data = []byte(r.mask(string(b.data)))
+	return &out
+}
+
+// encodeForm encodes a redacted form, sorted by key. Redacted values are
+// written unescaped, to be readable.
+func encodeForm(form map[string]any) []byte {
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b []byte
+	add := func(k string, v any) {
+		s, _ := v.(string)
+		if len(b) > 0 {
+			b = append(b, '&')
+		}
+		b = append(b, url.QueryEscape(k)...)
+		b = append(b, '=')
+		if s == redactedValue {
+			b = append(b, s...)
+		} else {
+			b = append(b, url.QueryEscape(s)...)
+		}
+	}
+	for _, k := range keys {
+		if values, ok := form[k].([]any); ok {
+			for _, v := range values {
+				add(k, v)
+			}
+		} else {
+			add(k, form[k])
+		}
+	}
+	return b
+}
+
+// LogBodies returns HTTP middleware logging the request and response bodies
+// of each request, with its method, path, status and duration, once the
+// handler returns. Wrap only the routes whose bodies are worth logging.
+//
+// Bodies are captured as the handler reads and writes them, without
+// buffering them or changing what the handler sees, and up to 64 KiB of
+// each is kept for redaction. They are then logged with Body, as
+// "request_body" and "response_body".
+//
+// The records report the caller of the middleware's ServeHTTP, such as the
+// router or middleware serving the route, as their source, by skipping the
+// frames of the middleware; see WithCallerSkip.
+func LogBodies(l Logger, opts BodyLogOptions) func(http.Handler) http.Handler {
+	// The record is logged by the handler function, called by the
+	// ServeHTTP method of http.HandlerFunc
+	l = WithCallerSkip(l, 2)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			var req *bodyCapture
+			if r.Body != nil && r.Body != http.NoBody {
+				req = &bodyCapture{ReadCloser: r.Body}
+				r.Body = req
+			}
+			rw := &bodyResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rw.status),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if req != nil && req.size > 0 {
+				attrs = append(attrs, slog.Any("request_body",
+					newBodyValue(req.buf.Bytes(), req.size, r.Header.Get("Content-Type"), opts)))
+			}
+			if rw.body.size > 0 {
+				attrs = append(attrs, slog.Any("response_body",
+					newBodyValue(rw.body.buf.Bytes(), rw.body.size, w.Header().Get("Content-Type"), opts)))
+			}
+			l.LogAttrs(r.Context(), opts.Level, "http bodies", attrs...)
+		})
+	}
+}
+
+// capturedBody keeps the first bytes of a body and counts them all.
+type capturedBody struct {
+	buf  bytes.Buffer
+	size int
+}
+
+func (c *capturedBody) capture(p []byte) {
+	c.size += len(p)
+	if room := bodyCaptureLimit - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(len(p), room)])
+	}
+}
+
+// bodyCapture captures a request body as it is read.
+type bodyCapture struct {
+	io.ReadCloser
+	capturedBody
+}
+
+func (c *bodyCapture) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.capture(p[:n])
+	return n, err
+}
+
+// bodyResponseWriter captures a response body and status as they are
+// written.
+type bodyResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   capturedBody
+}
+
+func (w *bodyResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *bodyResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.body.capture(p[:n])
+	return n, err
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *bodyResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
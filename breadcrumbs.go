@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// WithSentryHub returns a context carrying a Sentry hub of its own, cloned
+// from the current hub. Breadcrumbs and events for records logged with the
+// context go to that hub, so concurrent requests never see each other's
+// breadcrumbs. If ctx already carries a hub, such as one set by the
+// sentryhttp middleware, ctx is returned unchanged.
+func WithSentryHub(ctx context.Context) context.Context {
+	if sentry.HasHubOnContext(ctx) {
+		return ctx
+	}
+	return sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone())
+}
+
+// hubFromContext returns the Sentry hub of ctx, or the current hub.
+func hubFromContext(ctx context.Context) *sentry.Hub {
+	if ctx != nil {
+		if hub := sentry.GetHubFromContext(ctx); hub != nil {
+			return hub
+		}
+	}
+	return sentry.CurrentHub()
+}
+
+// addBreadcrumb records the record as a breadcrumb on the hub of ctx. Records
+// logged without a hub on their context are not recorded, since the global
+// hub is shared by all requests.
+func (h *sentryHandler) addBreadcrumb(ctx context.Context, record slog.Record) {
+	if ctx == nil {
+		return
+	}
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		return
+	}
+
+	var data map[string]interface{}
+	if n := len(h.attrs) + record.NumAttrs(); n > 0 {
+		data = make(map[string]interface{}, n)
+		h.eachAttr(record, func(key string, v any) {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			data[key] = v
+		})
+	}
+
+	hub.AddBreadcrumb(&sentry.Breadcrumb{
+		Type:      "default",
+		Category:  "log",
+		Message:   record.Message,
+		Data:      data,
+		Level:     slogToSentryLevel(record.Level),
+		Timestamp: record.Time,
+	}, nil)
+}
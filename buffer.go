@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBufferSize    = 4096
+	defaultFlushInterval = time.Second
+)
+
+// bufferedWriter wraps an io.Writer in a bufio.Writer that is flushed
+// periodically by a background goroutine and once more on Close.
+type bufferedWriter struct {
+	mu   sync.Mutex
+	w    *bufio.Writer
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// newBufferedWriter returns a bufferedWriter around w. Non-positive size and
+// interval fall back to the package defaults.
+func newBufferedWriter(w io.Writer, size int, interval time.Duration) *bufferedWriter {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+
+	bw := &bufferedWriter{
+		w:    bufio.NewWriterSize(w, size),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go bw.flushLoop(interval)
+	return bw
+}
+
+// Write buffers p, flushing to the underlying writer when the buffer is full.
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.w.Write(p)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (b *bufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.w.Flush()
+}
+
+// Close stops the periodic flush and flushes the remaining buffered data.
+func (b *bufferedWriter) Close() error {
+	b.once.Do(func() {
+		close(b.stop)
+		<-b.done
+	})
+	return b.Flush()
+}
+
+func (b *bufferedWriter) flushLoop(interval time.Duration) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = b.Flush()
+		case <-b.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"log/slog"
+	"runtime"
+)
+
+// callerSkipKey is the key of the attribute added by WithCallerSkip.
+const callerSkipKey = "caller_skip"
+
+// callerSkip is the value of an attribute that adds frames to skip when
+// reporting the source of records, instead of being logged.
+type callerSkip int
+
+// maxCallerDepth bounds how far up the stack the call site of a record is
+// looked for when skipping frames.
+const maxCallerDepth = 64
+
+// WithCallerSkip returns a logger that reports the source of its records
+// skip frames further up the stack than the call to the logging method, so
+// that records logged by a wrapper function point to the wrapper's caller.
+// Skips accumulate: each wrapper layer adds its own.
+//
+//	func logRequest(l logger.Logger, msg string) {
+//		logger.WithCallerSkip(l, 1).Info(msg) // source is logRequest's caller
+//	}
+//
+// Helpers of this package that log on the caller's behalf, such as Observe,
+// add the skip they need themselves.
+func WithCallerSkip(l Logger, skip int) Logger {
+	if skip <= 0 {
+		return l
+	}
+	return l.With(slog.Any(callerSkipKey, callerSkip(skip)))
+}
+
+// splitCallerSkip removes the caller skip attributes from attrs and returns
+// the remaining attributes and the sum of the skips.
+func splitCallerSkip(attrs []slog.Attr) ([]slog.Attr, int) {
+	skip, n := 0, 0
+	for _, a := range attrs {
+		if s, ok := a.Value.Any().(callerSkip); ok {
+			skip += int(s)
+			n++
+		}
+	}
+	if n == 0 {
+		return attrs, 0
+	}
+
+	kept := make([]slog.Attr, 0, len(attrs)-n)
+	for _, a := range attrs {
+		if _, ok := a.Value.Any().(callerSkip); !ok {
+			kept = append(kept, a)
+		}
+	}
+	return kept, skip
+}
+
+// skipCallers returns the program counter of the frame skip frames above
+// the one of pc, which must be on the current stack, as when a handler is
+// called synchronously by the logging method. Inlined calls count as frames.
+// If pc is not found, or the stack is not deep enough, pc is returned.
+func skipCallers(pc uintptr, skip int) uintptr {
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for i := 0; i < n; i++ {
+		if pcs[i] != pc {
+			continue
+		}
+		frames := runtime.CallersFrames(pcs[i:n])
+		frame, more := frames.Next()
+		for ; skip > 0; skip-- {
+			if !more {
+				return pc
+			}
+			frame, more = frames.Next()
+		}
+		// Frame.PC is the call instruction; report it as a return address,
+		// like the PCs of records
+		return frame.PC + 1
+	}
+	return pc
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+// logThrough logs a debug record through a wrapper function, as loggers
+// configured with a caller skip of 1 are called.
+//
+//go:noinline
+func logThrough(l Logger, i int) {
+	l.Debug("polled", "iteration", i, "status", 200)
+}
+
+func BenchmarkDeferSource(b *testing.B) {
+	escalations := []EscalationRule{{Attr: "status", Op: ">=", Value: 500, Level: slog.LevelError}}
+	benchmarks := []struct {
+		name        string
+		escalations []EscalationRule
+		deferred    bool
+	}{
+		{name: "early sampling"},
+		{name: "early sampling/deferred", deferred: true},
+		{name: "late sampling", escalations: escalations},
+		{name: "late sampling/deferred", escalations: escalations, deferred: true},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			l, err := New(Config{
+				LogLevel:        "debug",
+				Format:          "json",
+				Output:          io.Discard,
+				SampleRate:      0.01,
+				CallerSkip:      1,
+				DeferSource:     bm.deferred,
+				EscalationRules: bm.escalations,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer Close(l)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logThrough(l, i)
+			}
+		})
+	}
+}
+
+func TestLogBodiesSource(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	h := LogBodies(l, BodyLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	_, file, line, _ := runtime.Caller(0)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var record struct {
+		Source slog.Source `json:"source"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.String(), err)
+	}
+	if record.Source.File != file || record.Source.Line != line+1 {
+		t.Errorf("source is %s:%d, want the call to ServeHTTP at %s:%d",
+			record.Source.File, record.Source.Line, file, line+1)
+	}
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// maxDetachedCaptures bounds the Sentry captures for records logged with a
+// done context that may be in flight at once. Events beyond it are dropped.
+const maxDetachedCaptures = 64
+
+// canceledSentryMode reports whether records logged with a done context are
+// skipped for Sentry, for the Config.SentryCanceledContexts value name.
+func canceledSentryMode(name string) (skip bool, err error) {
+	switch name {
+	case "", "async":
+		return false, nil
+	case "skip":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported Sentry canceled context mode %q", name)
+	}
+}
+
+// contextDone reports whether ctx has been canceled or its deadline has
+// passed.
+func contextDone(ctx context.Context) bool {
+	return ctx != nil && ctx.Err() != nil
+}
+
+// captureDetached sends the event from a background goroutine, so that the
+// logging call does not wait for the Sentry transport, which blocks while it
+// is being flushed. The event, and its stack trace, are built beforehand.
+func (h *sentryHandler) captureDetached(client *sentry.Client, message string, scope *sentry.Scope) {
+	if h.detached.Add(1) > maxDetachedCaptures {
+		h.detached.Add(-1)
+		return
+	}
+
+	event := client.EventFromMessage(message, sentry.LevelInfo)
+	go func() {
+		defer h.detached.Add(-1)
+		client.CaptureEvent(event, nil, scope)
+	}()
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// carrierAttrPrefix prefixes the carrier keys of context attributes.
+	carrierAttrPrefix = "log-attr-"
+	// carrierBaggagePrefix prefixes the carrier keys of baggage entries.
+	carrierBaggagePrefix = "log-baggage-"
+)
+
+type ctxRemoteTraceKey struct{}
+
+// remoteTrace is the trace of the context a carrier was injected from.
+type remoteTrace struct {
+	header  string // sentry-trace header value
+	baggage string // baggage header value
+}
+
+// InjectContext writes the logging context of ctx to carrier, so that work
+// handed to another goroutine, process or service, such as through a job
+// queue, can be logged with the same context. The carrier receives:
+//
+//   - the attributes added with WithAttrs and the values of registered
+//     context keys, as "log-attr-<key>", grouped keys joined by dots;
+//   - the entries added with WithBaggage, as "log-baggage-<key>";
+//   - the Sentry trace of the span in ctx, as "sentry-trace" and "baggage".
+//
+// Values are written in their string form. Existing entries with the same
+// keys are overwritten.
+func InjectContext(ctx context.Context, carrier map[string]string) {
+	if ctx == nil {
+		return
+	}
+	for _, a := range contextAttrs(ctx) {
+		eachFlatAttr("", a, func(key string, v any) {
+			carrier[carrierAttrPrefix+key] = fmt.Sprint(v)
+		})
+	}
+	if keys := registry.Load(); keys != nil {
+		for _, k := range *keys {
+			if v := ctx.Value(k.key); v != nil {
+				carrier[carrierAttrPrefix+k.attr] = fmt.Sprint(v)
+			}
+		}
+	}
+	for k, v := range contextBaggage(ctx) {
+		carrier[carrierBaggagePrefix+k] = v
+	}
+
+	if span := sentry.SpanFromContext(ctx); span != nil {
+		carrier[sentry.SentryTraceHeader] = span.ToSentryTrace()
+		if baggage := span.ToBaggage(); baggage != "" {
+			carrier[sentry.SentryBaggageHeader] = baggage
+		}
+	} else if remote, ok := ctx.Value(ctxRemoteTraceKey{}).(remoteTrace); ok {
+		carrier[sentry.SentryTraceHeader] = remote.header
+		if remote.baggage != "" {
+			carrier[sentry.SentryBaggageHeader] = remote.baggage
+		}
+	}
+}
+
+// ExtractContext returns a copy of ctx carrying the logging context written
+// to carrier by InjectContext: its attributes are added to ctx as with
+// WithAttrs, in key order, its baggage as with WithBaggage, and its trace is
+// continued. Registered context keys are restored as plain attributes, not
+// as context values. Entries of other keys are ignored.
+//
+// Records logged with the returned context carry the same attributes as
+// those logged with the injected one. Observe continues the trace in a new
+// transaction, and TraceEnricher reports the carried trace ID until a span
+// is started.
+func ExtractContext(ctx context.Context, carrier map[string]string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var keys []string
+	baggage := map[string]string{}
+	for k, v := range carrier {
+		if key, ok := strings.CutPrefix(k, carrierAttrPrefix); ok && key != "" {
+			keys = append(keys, key)
+		} else if key, ok := strings.CutPrefix(k, carrierBaggagePrefix); ok && key != "" {
+			baggage[key] = v
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		args := make([]any, len(keys))
+		for i, key := range keys {
+			args[i] = slog.String(key, carrier[carrierAttrPrefix+key])
+		}
+		ctx = WithAttrs(ctx, args...)
+	}
+	if len(baggage) > 0 {
+		ctx = WithBaggage(ctx, baggage)
+	}
+	if header := carrier[sentry.SentryTraceHeader]; header != "" {
+		ctx = context.WithValue(ctx, ctxRemoteTraceKey{}, remoteTrace{
+			header:  header,
+			baggage: carrier[sentry.SentryBaggageHeader],
+		})
+	}
+	return ctx
+}
+
+// remoteTraceFromContext returns the trace extracted into ctx, if any, and
+// its trace ID, parent span ID and sampling decision, parsed from the
+// "<trace>-<span>[-<sampled>]" header.
+func remoteTraceFromContext(ctx context.Context) (remote remoteTrace, traceID, spanID string, sampled, ok bool) {
+	if ctx == nil {
+		return remote, "", "", false, false
+	}
+	remote, ok = ctx.Value(ctxRemoteTraceKey{}).(remoteTrace)
+	if !ok {
+		return remote, "", "", false, false
+	}
+	parts := strings.Split(remote.header, "-")
+	if len(parts) < 2 || len(parts[0]) != 32 || len(parts[1]) != 16 {
+		return remote, "", "", false, false
+	}
+	return remote, parts[0], parts[1], len(parts) > 2 && parts[2] == "1", true
+}
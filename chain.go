@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// chainKey is the field holding the checksum of a record.
+const chainKey = "chain"
+
+// chainWriter adds to each record a checksum chaining it to the previous
+// one: the HMAC-SHA256 of the previous checksum followed by the record as
+// encoded by the output handler, or their SHA-256 without a key. The first
+// record is chained to 32 zero bytes. Records are patched like by
+// sizeWriter, and the record hashed is the line without the checksum field
+// and newline.
+type chainWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	json bool
+	mac  hash.Hash
+	prev []byte
+}
+
+// newChainWriter returns a chainWriter for the output of the configuration,
+// or an error if its records cannot be patched.
+func newChainWriter(w io.Writer, config Config) (*chainWriter, error) {
+	format := outputFormat(config)
+	if format == "text" && config.TextIndent > 0 {
+		return nil, fmt.Errorf("integrity chain is not supported with indented text")
+	}
+	sw := newSizeWriter(w, format)
+	if sw == nil {
+		return nil, fmt.Errorf("integrity chain is not supported with format %q", format)
+	}
+	return &chainWriter{w: w, json: sw.json, mac: newChainHash(config.IntegrityKey), prev: make([]byte, sha256.Size)}, nil
+}
+
+// newChainHash returns the hash of the checksums for key.
+func newChainHash(key []byte) hash.Hash {
+	if len(key) == 0 {
+		return sha256.New()
+	}
+	return hmac.New(sha256.New, key)
+}
+
+// Write writes the record p with its checksum added as the last field.
+func (c *chainWriter) Write(p []byte) (int, error) {
+	line, ok := bytes.CutSuffix(p, []byte("\n"))
+	if !ok || (c.json && !bytes.HasSuffix(line, []byte("}"))) {
+		return c.w.Write(p)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sum := chainSum(c.mac, c.prev, line)
+	value := hex.EncodeToString(sum)
+	if c.json {
+		value = `"` + value + `"`
+	}
+	buf := appendField(make([]byte, 0, len(p)+len(chainKey)+2*sha256.Size+8), line, c.json, chainKey, value)
+	if _, err := c.w.Write(append(buf, '\n')); err != nil {
+		return 0, err
+	}
+	c.prev = sum
+	return len(p), nil
+}
+
+// chainSum returns the checksum of line chained to prev.
+func chainSum(mac hash.Hash, prev, line []byte) []byte {
+	mac.Reset()
+	mac.Write(prev)
+	mac.Write(line)
+	return mac.Sum(nil)
+}
+
+// VerifyChain reads records written with an integrity chain, in the json,
+// gcp or text format, wrapped under a JSONRootKey or not, and checks that each one's checksum chains it to the
+// previous one, using the IntegrityKey of the logger, if any. It returns
+// the number of records verified, and an error identifying the first line
+// that was modified, inserted or follows removed records.
+//
+// Records must be read from the first to be verified from the start of the
+// chain. Blank lines are ignored.
+func VerifyChain(r io.Reader, key []byte) (int, error) {
+	mac := newChainHash(key)
+	prev := make([]byte, sha256.Size)
+	jsonSuffix := len(`,"`+chainKey+`":""}`) + 2*sha256.Size
+	textSuffix := len(" "+chainKey+"=") + 2*sha256.Size
+	jsonChained := func(line []byte) bool {
+		return line[0] == '{' && len(line) > jsonSuffix &&
+			bytes.HasPrefix(line[len(line)-jsonSuffix:], []byte(`,"`+chainKey+`":"`))
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	n := 0
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		// With a JSONRootKey, the checksum is the last member of the
+		// wrapped record
+		if inner, ok := unwrapRootKey(line); ok && !jsonChained(line) && jsonChained(inner) {
+			line = inner
+		}
+
+		var record, encoded []byte
+		switch {
+		case jsonChained(line):
+			record = append(bytes.Clone(line[:len(line)-jsonSuffix]), '}')
+			encoded = line[len(line)-jsonSuffix+len(`,"`+chainKey+`":"`) : len(line)-2]
+		case len(line) > textSuffix && bytes.HasPrefix(line[len(line)-textSuffix:], []byte(" "+chainKey+"=")):
+			record = line[:len(line)-textSuffix]
+			encoded = line[len(line)-2*sha256.Size:]
+		default:
+			return n, fmt.Errorf("line %d: no checksum", lineNo)
+		}
+
+		want, err := hex.DecodeString(string(encoded))
+		if err != nil {
+			return n, fmt.Errorf("line %d: invalid checksum: %w", lineNo, err)
+		}
+		sum := chainSum(mac, prev, record)
+		if !hmac.Equal(sum, want) {
+			return n, fmt.Errorf("line %d: checksum mismatch", lineNo)
+		}
+		prev = sum
+		n++
+	}
+	return n, scanner.Err()
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type (
+	ctxAttrsKey   struct{}
+	ctxBaggageKey struct{}
+)
+
+// WithAttrs returns a context carrying attributes that are added to every
+// record logged with it. Attributes accumulate across calls; args are
+// interpreted as in slog.Logger.Log.
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	attrs := contextAttrs(ctx)
+	attrs = append(attrs[:len(attrs):len(attrs)], argsToAttrs(args)...)
+	return context.WithValue(ctx, ctxAttrsKey{}, attrs)
+}
+
+// WithBaggage returns a context carrying cross-service metadata, such as
+// OpenTelemetry baggage or gRPC metadata. Entries whose keys are listed in
+// Config.BaggageKeys are added to every record logged with the context;
+// the rest are ignored. Entries are merged over those already in ctx.
+func WithBaggage(ctx context.Context, baggage map[string]string) context.Context {
+	merged := make(map[string]string, len(baggage))
+	for k, v := range contextBaggage(ctx) {
+		merged[k] = v
+	}
+	for k, v := range baggage {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, ctxBaggageKey{}, merged)
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+func contextBaggage(ctx context.Context) map[string]string {
+	baggage, _ := ctx.Value(ctxBaggageKey{}).(map[string]string)
+	return baggage
+}
+
+// mergeContext adds the context attributes and allowed baggage entries to
+// the record. Attributes already on the record take precedence, then context
+// attributes, then baggage.
+func mergeContext(ctx context.Context, record slog.Record, baggageKeys []string) slog.Record {
+	if ctx == nil {
+		return record
+	}
+	attrs := contextAttrs(ctx)
+	baggage := contextBaggage(ctx)
+	if len(attrs) == 0 && (len(baggage) == 0 || len(baggageKeys) == 0) {
+		return record
+	}
+
+	present := make(map[string]bool, record.NumAttrs()+len(attrs))
+	record.Attrs(func(a slog.Attr) bool {
+		present[a.Key] = true
+		return true
+	})
+
+	record = record.Clone()
+	for _, a := range attrs {
+		if !present[a.Key] {
+			record.AddAttrs(a)
+			present[a.Key] = true
+		}
+	}
+	for _, k := range baggageKeys {
+		if v, ok := baggage[k]; ok && !present[k] {
+			record.AddAttrs(slog.String(k, v))
+		}
+	}
+	return record
+}
+
+// argsToAttrs converts alternating key-value pairs and attributes to a
+// slice of attributes, as slog.Logger does.
+func argsToAttrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCSVColumns is the column set used when Config.CSVColumns is empty.
+var defaultCSVColumns = []string{slog.TimeKey, slog.LevelKey, slog.MessageKey}
+
+// csvAttrsColumn is the trailing column holding packed attributes.
+const csvAttrsColumn = "attrs"
+
+// csvHandler is a slog.Handler that writes each record as one CSV row with
+// a fixed set of columns. Columns name either a built-in field (time, level,
+// msg, source) or an attribute key, with group names joined by dots.
+// Attributes without a column are dropped, or packed as a JSON object into a
+// trailing column when pack is set.
+type csvHandler struct {
+	opts    slog.HandlerOptions
+	columns []string
+	pack    bool
+	attrs   []csvField
+	groups  []string
+	mu      *sync.Mutex
+	w       io.Writer
+}
+
+// csvField is an attribute flattened to its dotted key.
+type csvField struct {
+	key   string
+	value slog.Value
+}
+
+// newCSVHandler returns a csvHandler writing to w, writing a header row
+// first if header is set.
+func newCSVHandler(w io.Writer, opts *slog.HandlerOptions, columns []string, header, pack bool) (*csvHandler, error) {
+	if len(columns) == 0 {
+		columns = defaultCSVColumns
+	}
+	h := &csvHandler{
+		columns: columns,
+		pack:    pack,
+		mu:      &sync.Mutex{},
+		w:       w,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	if header {
+		row := columns
+		if pack {
+			row = append(columns[:len(columns):len(columns)], csvAttrsColumn)
+		}
+		if err := h.writeRow(row); err != nil {
+			return nil, fmt.Errorf("writing CSV header: %w", err)
+		}
+	}
+	return h, nil
+}
+
+// Enabled reports whether the level is at or above the configured minimum.
+func (h *csvHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle writes the record as a CSV row.
+func (h *csvHandler) Handle(_ context.Context, record slog.Record) error {
+	fields := make([]csvField, 0, len(h.attrs)+record.NumAttrs()+4)
+
+	builtin := []slog.Attr{
+		slog.Time(slog.TimeKey, record.Time),
+		slog.Any(slog.LevelKey, record.Level),
+		slog.String(slog.MessageKey, record.Message),
+	}
+	if h.opts.AddSource && record.PC != 0 {
+		if src := recordSource(record); src != nil {
+			builtin = append(builtin, slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", src.File, src.Line)))
+		}
+	}
+	for _, a := range builtin {
+		if h.opts.ReplaceAttr != nil {
+			a = h.opts.ReplaceAttr(nil, a)
+		}
+		a.Value = a.Value.Resolve()
+		switch {
+		case a.Value.Kind() == slog.KindGroup && a.Key == "":
+			// A built-in replaced by an inlined group, such as time and
+			// local_time.
+			for _, ga := range a.Value.Group() {
+				fields = append(fields, csvField{key: ga.Key, value: ga.Value.Resolve()})
+			}
+		case a.Key != "":
+			fields = append(fields, csvField{key: a.Key, value: a.Value})
+		}
+	}
+
+	fields = append(fields, h.attrs...)
+	record.Attrs(func(a slog.Attr) bool {
+		fields = h.flatten(fields, h.groups, a)
+		return true
+	})
+
+	values := make(map[string]slog.Value, len(fields))
+	for _, f := range fields {
+		values[f.key] = f.value
+	}
+
+	row := make([]string, len(h.columns), len(h.columns)+1)
+	inColumns := make(map[string]bool, len(h.columns))
+	for i, col := range h.columns {
+		inColumns[col] = true
+		if v, ok := values[col]; ok {
+			row[i] = csvValue(v)
+		}
+	}
+
+	if h.pack {
+		extra := map[string]any{}
+		for _, f := range fields {
+			if !inColumns[f.key] && !isBuiltinKey(f.key) {
+				extra[f.key] = f.value.Any()
+			}
+		}
+		packed := ""
+		if len(extra) > 0 {
+			b, err := json.Marshal(extra)
+			if err != nil {
+				return err
+			}
+			packed = string(b)
+		}
+		row = append(row, packed)
+	}
+
+	return h.writeRow(row)
+}
+
+// WithAttrs returns a new handler with the given attributes.
+func (h *csvHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = h.attrs[:len(h.attrs):len(h.attrs)]
+	for _, a := range attrs {
+		h2.attrs = h.flatten(h2.attrs, h.groups, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a new handler with the given group name.
+func (h *csvHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
+
+// flatten appends a to fields, expanding groups into dotted keys.
+func (h *csvHandler) flatten(fields []csvField, groups []string, a slog.Attr) []csvField {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		if len(group) == 0 {
+			return fields
+		}
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range group {
+			fields = h.flatten(fields, groups, ga)
+		}
+		return fields
+	}
+
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Key == "" {
+		return fields
+	}
+
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + a.Key
+	}
+	return append(fields, csvField{key: key, value: a.Value})
+}
+
+// writeRow encodes row as a CSV line and writes it in a single call.
+func (h *csvHandler) writeRow(row []string) error {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// csvValue formats a value for a CSV cell.
+func csvValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case error:
+			return a.Error()
+		case fmt.Stringer:
+			return a.String()
+		}
+		if b, err := json.Marshal(v.Any()); err == nil {
+			return string(b)
+		}
+	}
+	return v.String()
+}
+
+func isBuiltinKey(key string) bool {
+	switch key {
+	case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+		return true
+	}
+	return false
+}
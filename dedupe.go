@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+type seenErrorsKey struct{}
+
+// WithErrorDedupe returns a context that deduplicates Sentry events for
+// errors logged with it. Once a record carrying an error has been sent to
+// Sentry, later records in the same context whose errors are or wrap it are
+// still logged but no longer sent to Sentry. Errors that merely share a
+// wrapped error, such as io.EOF, are unrelated and each reported.
+//
+// Call it once per request, typically in middleware, so that an error logged
+// at several layers as it propagates produces a single Sentry event.
+func WithErrorDedupe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, seenErrorsKey{}, &seenErrors{seen: map[any]struct{}{}})
+}
+
+// seenErrors records the errors of the records already reported within a
+// context, as logged, not the errors they wrap.
+type seenErrors struct {
+	mu   sync.Mutex
+	seen map[any]struct{}
+}
+
+func seenErrorsFromContext(ctx context.Context) *seenErrors {
+	s, _ := ctx.Value(seenErrorsKey{}).(*seenErrors)
+	return s
+}
+
+// report marks the errors carried by a record as seen. It reports whether
+// the record should be sent, which is the case unless every error is or
+// wraps an error seen before. A nil receiver reports true.
+func (s *seenErrors) report(errs []error) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found, fresh bool
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		found = true
+
+		dup := false
+		for _, e := range errorChain(err) {
+			if _, ok := s.seen[errorKey(e)]; ok {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			fresh = true
+		}
+		s.seen[errorKey(err)] = struct{}{}
+	}
+	return !found || fresh
+}
+
+// errorChain returns err and every error it wraps, depth first.
+func errorChain(err error) []error {
+	var chain []error
+	var walk func(error)
+	walk = func(e error) {
+		if e == nil {
+			return
+		}
+		chain = append(chain, e)
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				walk(inner)
+			}
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// errorFingerprint identifies errors that cannot be used as map keys.
+type errorFingerprint struct {
+	typ reflect.Type
+	msg string
+}
+
+// errorKey returns a map key identifying err: the error itself, by pointer
+// identity, when its dynamic type is a pointer, otherwise its type and
+// message. Other comparable types may hold unhashable values in interface
+// fields, so they are not used as keys.
+func errorKey(err error) any {
+	t := reflect.TypeOf(err)
+	if t.Kind() == reflect.Pointer {
+		return err
+	}
+	return errorFingerprint{typ: t, msg: err.Error()}
+}
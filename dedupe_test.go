@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// partsError is a comparable error type that is not a pointer, whose
+// values cannot be map keys because of its slice field.
+type partsError struct {
+	parts []string
+}
+
+func (e partsError) Error() string { return strings.Join(e.parts, ": ") }
+
+func TestErrorDedupe(t *testing.T) {
+	transport := &countingTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hubCtx := sentry.SetHubOnContext(context.Background(), sentry.NewHub(client, sentry.NewScope()))
+	h := &sentryHandler{minLogLevel: slog.LevelError}
+
+	base := &os.PathError{Op: "open", Path: "/etc/app.conf", Err: os.ErrNotExist}
+	tests := []struct {
+		name string
+		errs []error // Logged in this order, each in its own record
+		want int64   // Events sent
+	}{
+		{name: "same error", errs: []error{base, base}, want: 1},
+		{name: "wrapped", errs: []error{base, fmt.Errorf("loading config: %w", base)}, want: 1},
+		{name: "joined", errs: []error{base, errors.Join(errors.New("other"), base)}, want: 1},
+		{name: "wrapper first", errs: []error{fmt.Errorf("loading config: %w", base), base}, want: 2},
+		{name: "shared cause", errs: []error{fmt.Errorf("a: %w", io.EOF), fmt.Errorf("b: %w", io.EOF)}, want: 2},
+		{name: "unhashable", errs: []error{partsError{[]string{"a", "b"}}, partsError{[]string{"a", "b"}}}, want: 1},
+		{name: "unhashable differing", errs: []error{partsError{[]string{"a"}}, partsError{[]string{"b"}}}, want: 2},
+		{name: "no error", errs: []error{nil, nil}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, dedupe := range []bool{true, false} {
+				ctx := hubCtx
+				want := int64(len(tt.errs))
+				if dedupe {
+					ctx = WithErrorDedupe(hubCtx)
+					want = tt.want
+				}
+				transport.events.Store(0)
+				for _, err := range tt.errs {
+					record := slog.NewRecord(time.Now(), slog.LevelError, "failed", 0)
+					if err != nil {
+						record.AddAttrs(slog.Any("error", err))
+					}
+					h.capture(ctx, record)
+				}
+				if got := transport.events.Load(); got != want {
+					t.Errorf("dedupe %t: sent %d events, want %d", dedupe, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package logger
+
+import "reflect"
+
+// Derive returns a copy of c with the fields set in overrides replacing
+// its own, to configure the loggers of several components from a base
+// configuration:
+//
+//	base := logger.Config{LogLevel: "info", Format: "json", EnableSentry: true}
+//	db, err := logger.New(base.Derive(logger.Config{LogLevel: "debug"}))
+//
+// A field is set in overrides if it is not the zero value of its type. Set
+// slices and maps replace those of c as a whole rather than being merged,
+// so overrides can drop entries; an empty but non-nil slice or map clears
+// them. Zero values cannot override the fields of c: a derived config
+// cannot turn off a bool set in the base, or reset a number to 0, and
+// should start from a separate base instead. Derived configs can be
+// derived again, the last overrides taking precedence.
+func (c Config) Derive(overrides Config) Config {
+	out := c
+	dst := reflect.ValueOf(&out).Elem()
+	src := reflect.ValueOf(overrides)
+	for i := 0; i < src.NumField(); i++ {
+		f := src.Field(i)
+		switch f.Kind() {
+		case reflect.Slice, reflect.Map:
+			if f.IsNil() {
+				continue
+			}
+		default:
+			if f.IsZero() {
+				continue
+			}
+		}
+		dst.Field(i).Set(f)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// dryRunTransport is a sentry.Transport that logs the events it is given as
+// JSON records instead of sending them to Sentry.
+type dryRunTransport struct {
+	logger *slog.Logger
+}
+
+// newDryRunTransport returns a dryRunTransport writing to w.
+func newDryRunTransport(w io.Writer) *dryRunTransport {
+	return &dryRunTransport{logger: slog.New(slog.NewJSONHandler(w, nil))}
+}
+
+// Configure implements sentry.Transport.
+func (t *dryRunTransport) Configure(sentry.ClientOptions) {}
+
+// Flush implements sentry.Transport. Events are logged as they are sent, so
+// there is nothing to flush.
+func (t *dryRunTransport) Flush(time.Duration) bool { return true }
+
+// SendEvent logs the event as a record with a "log_type" attribute set to
+// "sentry_dry_run", carrying the fields Sentry would have received.
+func (t *dryRunTransport) SendEvent(event *sentry.Event) {
+	attrs := []slog.Attr{
+		slog.String(logTypeKey, "sentry_dry_run"),
+		slog.String("event_id", string(event.EventID)),
+		slog.String("event_level", string(event.Level)),
+		slog.String("event_message", event.Message),
+	}
+	if event.Type != "" {
+		attrs = append(attrs, slog.String("event_type", event.Type))
+	}
+	if len(event.Tags) > 0 {
+		attrs = append(attrs, slog.Any("tags", event.Tags))
+	}
+	if len(event.Extra) > 0 {
+		attrs = append(attrs, slog.Any("extra", event.Extra))
+	}
+	if event.Release != "" {
+		attrs = append(attrs, slog.String("release", event.Release))
+	}
+	if event.Environment != "" {
+		attrs = append(attrs, slog.String("environment", event.Environment))
+	}
+	if len(event.Breadcrumbs) > 0 {
+		attrs = append(attrs, slog.Int("breadcrumbs", len(event.Breadcrumbs)))
+	}
+	if frames := stackFrames(event); frames > 0 {
+		attrs = append(attrs, slog.Int("stack_frames", frames))
+	}
+	t.logger.LogAttrs(context.Background(), slog.LevelInfo, "sentry event (dry run)", attrs...)
+}
+
+// stackFrames returns the number of frames in the stack traces of event.
+func stackFrames(event *sentry.Event) int {
+	n := 0
+	for _, thread := range event.Threads {
+		if thread.Stacktrace != nil {
+			n += len(thread.Stacktrace.Frames)
+		}
+	}
+	for _, exception := range event.Exception {
+		if exception.Stacktrace != nil {
+			n += len(exception.Stacktrace.Frames)
+		}
+	}
+	return n
+}
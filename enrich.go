@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Enricher adds attributes to records before they are emitted. Enrich is
+// called synchronously for every record that passes the level and sampling
+// checks, with the context the record was logged with, which may be nil.
+// Attributes it adds are placed like those passed to the logging call,
+// inside the groups of the logger. It must be safe for concurrent use and
+// should be cheap.
+type Enricher interface {
+	Enrich(ctx context.Context, record *slog.Record)
+}
+
+// EnricherFunc adapts a function to the Enricher interface.
+type EnricherFunc func(ctx context.Context, record *slog.Record)
+
+// Enrich calls f(ctx, record).
+func (f EnricherFunc) Enrich(ctx context.Context, record *slog.Record) { f(ctx, record) }
+
+// StaticEnricher returns an enricher adding the same attributes to every
+// record. args are interpreted as in slog.Logger.Log.
+func StaticEnricher(args ...any) Enricher {
+	attrs := argsToAttrs(args)
+	return EnricherFunc(func(_ context.Context, record *slog.Record) {
+		record.AddAttrs(attrs...)
+	})
+}
+
+// ContextValueEnricher returns an enricher adding the value stored in the
+// context under ctxKey, such as a user or tenant ID set by middleware, as an
+// attribute named key. Records whose context has no such value are left
+// unchanged.
+func ContextValueEnricher(key string, ctxKey any) Enricher {
+	return EnricherFunc(func(ctx context.Context, record *slog.Record) {
+		if ctx == nil {
+			return
+		}
+		if v := ctx.Value(ctxKey); v != nil {
+			record.AddAttrs(slog.Any(key, v))
+		}
+	})
+}
+
+// TraceEnricher returns an enricher adding the trace and span IDs of the
+// Sentry span in the context, as "trace_id" and "span_id" attributes, so
+// records can be correlated with traces. Without a span, it adds those of
+// the trace extracted with ExtractContext, the span being the one that
+// injected it.
+func TraceEnricher() Enricher {
+	return EnricherFunc(func(ctx context.Context, record *slog.Record) {
+		if ctx == nil {
+			return
+		}
+		if span := sentry.SpanFromContext(ctx); span != nil {
+			record.AddAttrs(
+				slog.String("trace_id", span.TraceID.String()),
+				slog.String("span_id", span.SpanID.String()),
+			)
+		} else if _, traceID, spanID, _, ok := remoteTraceFromContext(ctx); ok {
+			record.AddAttrs(slog.String("trace_id", traceID), slog.String("span_id", spanID))
+		}
+	})
+}
+
+// GoroutineEnricher returns an enricher adding the attributes fn returns
+// when a record is logged, for codebases that keep request state in
+// goroutine-local storage rather than in a context. fn is called on the
+// goroutine that logs the record, from the logging call, so it sees that
+// goroutine's state; it must be cheap, safe for concurrent use and must not
+// log. It is an escape hatch: state is not inherited by goroutines started
+// to handle part of a request, and goroutines reused across requests must
+// reset it, or records carry the state of a previous request. Prefer
+// passing contexts and WithAttrs where possible.
+func GoroutineEnricher(fn func() []slog.Attr) Enricher {
+	return EnricherFunc(func(_ context.Context, record *slog.Record) {
+		record.AddAttrs(fn()...)
+	})
+}
+
+// enrich runs the enrichers in order on a copy of the record.
+func enrich(ctx context.Context, record slog.Record, enrichers []Enricher) slog.Record {
+	if len(enrichers) == 0 {
+		return record
+	}
+	record = record.Clone()
+	for _, e := range enrichers {
+		e.Enrich(ctx, &record)
+	}
+	return record
+}
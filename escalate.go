@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// escalatedFromKey is the attribute holding the level of an escalated
+// record before escalation.
+const escalatedFromKey = "escalated_from"
+
+// EscalationRule raises the level of the records whose attribute matches a
+// condition, such as warnings with more than 3 retries to errors:
+//
+//	logger.EscalationRule{Attr: "retries", Op: ">", Value: 3, Level: slog.LevelError}
+type EscalationRule struct {
+	// Attr is the key of the attribute tested, with the keys of enclosing
+	// groups joined by dots, such as "http.status".
+	Attr string
+	// Op compares the attribute value to Value: ">", ">=", "<", "<=", "=="
+	// or "!=". Ordered comparisons require numeric values, durations being
+	// compared in nanoseconds; equality compares other values by their
+	// string form. Empty matches any record with the attribute.
+	Op string
+	// Value is the operand of Op.
+	Value any
+	// Level is the level matching records are raised to. Records already at
+	// or above it are left unchanged.
+	Level slog.Level
+}
+
+// escalationRule is a validated EscalationRule.
+type escalationRule struct {
+	EscalationRule
+	number  float64 // Value, if numeric
+	numeric bool
+}
+
+// newEscalationRules validates rules.
+func newEscalationRules(rules []EscalationRule) ([]escalationRule, error) {
+	out := make([]escalationRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Attr == "" {
+			return nil, fmt.Errorf("invalid escalation rule: empty attribute")
+		}
+		er := escalationRule{EscalationRule: r}
+		er.number, er.numeric = escalationNumber(r.Value)
+		switch r.Op {
+		case "", "==", "!=":
+		case ">", ">=", "<", "<=":
+			if !er.numeric {
+				return nil, fmt.Errorf("invalid escalation rule for %q: %s requires a number, got %T", r.Attr, r.Op, r.Value)
+			}
+		default:
+			return nil, fmt.Errorf("invalid escalation rule for %q: unsupported operator %q", r.Attr, r.Op)
+		}
+		out = append(out, er)
+	}
+	return out, nil
+}
+
+// escalationNumber returns v as a float64 if it is a number or a duration.
+func escalationNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case time.Duration:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// matches reports whether the attribute value v satisfies the rule.
+func (r escalationRule) matches(v any) bool {
+	if r.Op == "" {
+		return true
+	}
+	n, numeric := escalationNumber(v)
+	switch r.Op {
+	case "==":
+		if numeric && r.numeric {
+			return n == r.number
+		}
+		return fmt.Sprint(v) == fmt.Sprint(r.Value)
+	case "!=":
+		if numeric && r.numeric {
+			return n != r.number
+		}
+		return fmt.Sprint(v) != fmt.Sprint(r.Value)
+	}
+	if !numeric {
+		return false
+	}
+	switch r.Op {
+	case ">":
+		return n > r.number
+	case ">=":
+		return n >= r.number
+	case "<":
+		return n < r.number
+	default:
+		return n <= r.number
+	}
+}
+
+// escalate raises the level of the record to the highest level of the
+// rules matching its attributes or those added with WithAttrs, and returns
+// it with the name of the original level, for the "escalated_from" field,
+// or "" if it was not escalated.
+func (h *rootHandler) escalate(record slog.Record) (slog.Record, string) {
+	level := record.Level
+	test := func(key string, v any) {
+		for _, r := range h.escalations {
+			if r.Level > level && r.Attr == key && r.matches(v) {
+				level = r.Level
+			}
+		}
+	}
+
+	var prefix string
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			prefix += goa.group + "."
+			continue
+		}
+		for _, a := range goa.attrs {
+			eachFlatAttr(prefix, a, test)
+		}
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		eachFlatAttr(prefix, a, test)
+		return true
+	})
+	if level == record.Level {
+		return record, ""
+	}
+
+	from := levelName(record.Level)
+	record.Level = level
+	return record, from
+}
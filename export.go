@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ExportConfig returns the effective configuration of l as an indented JSON
+// object, for diagnostics and support tickets: every field of the Config it
+// was created with, zero or not, defaults resolved, such as the level, the
+// format and batch settings. Secrets, the Sentry DSNs, the Application
+// Insights connection string, the integrity key and credentials in the
+// Loki URL, are replaced with "[REDACTED]" when set. Functions and
+// interface values are reported by type, and durations as strings such as
+// "1s". It fails if l was not created by New.
+func ExportConfig(l Logger) ([]byte, error) {
+	h, ok := l.Handler().(*rootHandler)
+	if !ok {
+		return nil, errNotRootLogger
+	}
+	return json.MarshalIndent(exportedConfig(*h.config), "", "  ")
+}
+
+// effectiveConfig returns the configuration with the defaults New applies
+// to its fields filled in.
+func effectiveConfig(c Config) Config {
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		c.LogLevel = "info"
+	}
+	if c.Format = outputFormat(c); c.Format == "" {
+		c.Format = "json"
+	}
+	if c.BufferedOutput {
+		if c.BufferSize <= 0 {
+			c.BufferSize = defaultBufferSize
+		}
+		if c.FlushInterval <= 0 {
+			c.FlushInterval = defaultFlushInterval
+		}
+	}
+	if c.FIFOPath != "" && c.FIFOFallback == "" {
+		c.FIFOFallback = "stdout"
+	}
+	if c.RedactMaxDepth <= 0 {
+		c.RedactMaxDepth = defaultRedactMaxDepth
+	}
+	if c.EnableSentry {
+		if c.SentryMaxBreadcrumbs <= 0 {
+			c.SentryMaxBreadcrumbs = 30
+		}
+		if c.SentryCanceledContexts == "" {
+			c.SentryCanceledContexts = "async"
+		}
+		if c.StackTraceDepth <= 0 {
+			c.StackTraceDepth = defaultStackTraceDepth
+		}
+		if c.SentryQuietHours != "" && c.SentryQuietTimezone == "" {
+			c.SentryQuietTimezone = time.Local.String()
+		}
+	}
+	if c.Format == "gcp" && c.GCPProjectID == "" {
+		c.GCPProjectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if c.LokiURL != "" {
+		c.LokiBatchSize, c.LokiBatchWait = batchSettings(c, c.LokiBatchSize, c.LokiBatchWait)
+	}
+	if c.AzureConnectionString != "" {
+		c.AzureBatchSize, c.AzureBatchWait = batchSettings(c, c.AzureBatchSize, c.AzureBatchWait)
+	}
+	c.BatchSize, c.BatchWait = batchSettings(c, c.BatchSize, c.BatchWait)
+	if c.RateLimit > 0 && c.RateBurst < 1 {
+		c.RateBurst = int(max(math.Ceil(c.RateLimit), 1))
+	}
+	c.CallerSkip = max(c.CallerSkip, 0)
+	return c
+}
+
+// exportedConfig returns the fields of the configuration by name, with
+// secrets redacted and values that cannot be encoded described.
+func exportedConfig(c Config) map[string]any {
+	if c.SentryDSN != "" {
+		c.SentryDSN = redactedValue
+	}
+	if c.AzureConnectionString != "" {
+		c.AzureConnectionString = redactedValue
+	}
+	if len(c.SentryRoutes) > 0 {
+		routes := make(map[string]string, len(c.SentryRoutes))
+		for k := range c.SentryRoutes {
+			routes[k] = redactedValue
+		}
+		c.SentryRoutes = routes
+	}
+	if u, err := url.Parse(c.LokiURL); err == nil && u.User != nil {
+		u.User = nil
+		c.LokiURL = strings.Replace(u.String(), "://", "://"+redactedValue+"@", 1)
+	}
+
+	out := map[string]any{}
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		out[t.Field(i).Name] = exportedValue(v.Field(i))
+	}
+	if len(c.IntegrityKey) > 0 {
+		out["IntegrityKey"] = redactedValue
+	}
+	return out
+}
+
+// exportedValue returns a field value as encoded by ExportConfig.
+func exportedValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Func, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return fmt.Sprintf("%T", v.Interface())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if elem := v.Type().Elem().Kind(); elem == reflect.Func || elem == reflect.Interface {
+			types := make([]any, v.Len())
+			for i := range types {
+				types[i] = exportedValue(v.Index(i))
+			}
+			return types
+		}
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return v.Interface()
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// LevelFatal is the level of records logged by Fatal, above slog.LevelError.
+// It is written as "FATAL" and sent to Sentry as a fatal event.
+const LevelFatal = slog.Level(12)
+
+// exitFunc is the exit function of loggers without Config.ExitFunc.
+var exitFunc = os.Exit
+
+// Fatal logs msg at LevelFatal, closes the logger, which flushes buffered
+// output and pending Sentry events, and exits the process with status 1.
+// args are interpreted as in slog.Logger.Log.
+//
+// The process is exited with Config.ExitFunc when set, which lets tests
+// observe fatal errors without exiting. Fatal returns if the exit function
+// does, leaving the logger closed.
+func Fatal(l Logger, msg string, args ...any) {
+	fatal(context.Background(), l, msg, args...)
+}
+
+// FatalContext is like Fatal, logging with ctx.
+func FatalContext(ctx context.Context, l Logger, msg string, args ...any) {
+	fatal(ctx, l, msg, args...)
+}
+
+// fatal implements Fatal and FatalContext, reporting their caller as the
+// source of the record.
+func fatal(ctx context.Context, l Logger, msg string, args ...any) {
+	WithCallerSkip(l, 2).Log(ctx, LevelFatal, msg, args...)
+	_ = Close(l)
+	exit := exitFunc
+	if h, ok := l.Handler().(*rootHandler); ok && h.exit != nil {
+		exit = h.exit
+	}
+	exit(1)
+}
+
+// levelName returns the name of level, as written in records.
+func levelName(level slog.Level) string {
+	if level == LevelFatal {
+		return "FATAL"
+	}
+	return level.String()
+}
+
+// levelReplacer writes LevelFatal under its name rather than as "ERROR+4".
+func levelReplacer(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || a.Key != slog.LevelKey {
+		return a
+	}
+	if level, ok := a.Value.Any().(slog.Level); ok && level == LevelFatal {
+		a.Value = slog.StringValue(levelName(level))
+	}
+	return a
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// fifoReopenInterval is how often a disconnected FIFO is reopened.
+const fifoReopenInterval = time.Second
+
+// fifoFallback returns the writer receiving records the FIFO cannot take.
+func fifoFallback(name string) (io.Writer, error) {
+	switch name {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "discard":
+		return io.Discard, nil
+	default:
+		return nil, fmt.Errorf("unsupported FIFO fallback %q", name)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package logger
+
+import (
+	"errors"
+	"io"
+)
+
+// fifoWriter is unavailable on platforms without named pipes.
+type fifoWriter struct {
+	io.Writer
+}
+
+func newFIFOWriter(path string, fallback io.Writer) (*fifoWriter, error) {
+	return nil, errors.New("log FIFOs are not supported on this platform")
+}
+
+func (w *fifoWriter) Close() error { return nil }
@@ -0,0 +1,118 @@
+//go:build unix
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// fifoWriter writes records to a named pipe without ever blocking. The pipe
+// is opened in non-blocking mode; while no reader is attached, or when the
+// reader falls behind and the pipe is full, records go to the fallback
+// writer instead. A disconnected pipe is reopened at most once per
+// fifoReopenInterval, so a reader that reconnects starts receiving records
+// again.
+type fifoWriter struct {
+	path     string
+	fallback io.Writer
+
+	mu       sync.Mutex
+	fd       int // -1 while no reader is attached
+	nextOpen time.Time
+	closed   bool
+}
+
+// newFIFOWriter returns a fifoWriter for the named pipe at path.
+func newFIFOWriter(path string, fallback io.Writer) (*fifoWriter, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening log FIFO: %w", err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return nil, fmt.Errorf("opening log FIFO: %s is not a named pipe", path)
+	}
+
+	w := &fifoWriter{path: path, fallback: fallback, fd: -1}
+	w.open()
+	return w, nil
+}
+
+// open tries to open the pipe. It fails with ENXIO while there is no reader.
+func (w *fifoWriter) open() {
+	w.nextOpen = time.Now().Add(fifoReopenInterval)
+	fd, err := syscall.Open(w.path, syscall.O_WRONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+	w.fd = fd
+}
+
+// disconnect closes the pipe after its reader went away.
+func (w *fifoWriter) disconnect() {
+	syscall.Close(w.fd)
+	w.fd = -1
+}
+
+// Write writes p to the pipe, or to the fallback writer if the pipe has no
+// reader or is full.
+func (w *fifoWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fd < 0 && !w.closed && !time.Now().Before(w.nextOpen) {
+		w.open()
+	}
+	if w.fd < 0 {
+		return w.fallback.Write(p)
+	}
+
+	written := 0
+	for attempt := 0; written < len(p); attempt++ {
+		n, err := syscall.Write(w.fd, p[written:])
+		if n > 0 {
+			written += n
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, syscall.EAGAIN):
+			if written == 0 {
+				// The pipe is full; records up to PIPE_BUF are written
+				// atomically, so nothing of this one reached the reader.
+				return w.fallback.Write(p)
+			}
+			if attempt >= 100 {
+				return written, err
+			}
+			// Part of a large record went out; wait briefly for the
+			// reader rather than splitting it between outputs.
+			time.Sleep(time.Millisecond)
+		case errors.Is(err, syscall.EINTR):
+		default:
+			// EPIPE when the reader disconnected.
+			w.disconnect()
+			if written == 0 {
+				return w.fallback.Write(p)
+			}
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Close closes the pipe.
+func (w *fifoWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.fd >= 0 {
+		w.disconnect()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build unix
+
+package logger
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestFIFO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.fifo")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Skipf("creating a FIFO: %v", err)
+	}
+	reader, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fallback, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fallback.Close()
+	stdout := os.Stdout
+	os.Stdout = fallback
+	l, err := New(Config{Format: "json", FIFOPath: path, FIFOFallback: "stdout"})
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	l.Info("through the pipe")
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, `"msg":"through the pipe"`) {
+		t.Errorf("read %q from the FIFO", line)
+	}
+
+	// Without a reader, records go to the fallback rather than blocking.
+	reader.Close()
+	l.Info("to the fallback")
+	l.Info("to the fallback again")
+	b, err := os.ReadFile(fallback.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "to the fallback"); n != 2 {
+		t.Errorf("fallback got %d records, want 2:\n%s", n, b)
+	}
+}
+
+func TestFIFOInvalid(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "regular")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fifo := filepath.Join(dir, "log.fifo")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Skipf("creating a FIFO: %v", err)
+	}
+
+	for name, config := range map[string]Config{
+		"missing":      {FIFOPath: filepath.Join(dir, "missing")},
+		"regular file": {FIFOPath: file},
+		"fallback":     {FIFOPath: fifo, FIFOFallback: "stderr"},
+	} {
+		config.Output = io.Discard
+		if _, err := New(config); err == nil {
+			t.Errorf("%s: New accepted the FIFO configuration", name)
+		}
+	}
+}
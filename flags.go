@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// flagsKey is the group holding the feature flag states of a record.
+const flagsKey = "flags"
+
+// FlagSource reports the state of feature flags, as evaluated for the
+// request or user a context belongs to. Implementations typically wrap the
+// client of a feature flag service; they must be safe for concurrent use.
+type FlagSource interface {
+	// Flag returns the value of the flag named key, and whether it is known.
+	Flag(key string) (value any, ok bool)
+}
+
+// FlagMap is a FlagSource holding flag values in a map, such as a snapshot
+// of the flags evaluated for a request.
+type FlagMap map[string]any
+
+// Flag returns the value of the flag named key, and whether it is in m.
+func (m FlagMap) Flag(key string) (any, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+type ctxFlagsKey struct{}
+
+// WithFeatureFlags returns a context carrying the feature flag source whose
+// flags are logged by FeatureFlagEnricher.
+func WithFeatureFlags(ctx context.Context, src FlagSource) context.Context {
+	return context.WithValue(ctx, ctxFlagsKey{}, src)
+}
+
+// FeatureFlagEnricher returns an enricher adding the state of the flags
+// named by keys, as reported by the source attached to the context with
+// WithFeatureFlags, to every record logged with it. Only the listed flags
+// are logged, in a "flags" group, and also set as "flag.<key>" tags on
+// Sentry events. Flags the source does not know are left out, and records
+// whose context has no source are left unchanged.
+func FeatureFlagEnricher(keys ...string) Enricher {
+	return EnricherFunc(func(ctx context.Context, record *slog.Record) {
+		if ctx == nil {
+			return
+		}
+		src, _ := ctx.Value(ctxFlagsKey{}).(FlagSource)
+		if src == nil {
+			return
+		}
+
+		var flags []slog.Attr
+		for _, key := range keys {
+			if v, ok := src.Flag(key); ok {
+				flags = append(flags, slog.Any(key, v))
+				record.AddAttrs(SentryTag("flag."+key, fmt.Sprint(v)))
+			}
+		}
+		if len(flags) > 0 {
+			record.AddAttrs(slog.Attr{Key: flagsKey, Value: slog.GroupValue(flags...)})
+		}
+	})
+}
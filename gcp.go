@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Special fields of the Cloud Logging structured format.
+const (
+	gcpMessageKey        = "message"
+	gcpSeverityKey       = "severity"
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanIDKey         = "logging.googleapis.com/spanId"
+	gcpTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+// gcpHandler is a slog.Handler writing records as JSON in the Google Cloud
+// Logging structured format. Built-in fields are renamed by gcpReplacer, and
+// the trace of the Sentry span in the context, or of the trace extracted
+// into it, if any, is added at the top level, whatever the groups of the
+// logger.
+type gcpHandler struct {
+	json         slog.Handler
+	projectID    string
+	traceSampled func(ctx context.Context) (sampled, ok bool) // Config.TraceSampled
+	goas         []groupOrAttrs                               // added with WithGroup and WithAttrs, in order
+}
+
+// newGCPHandler returns a gcpHandler writing to w. Traces are qualified by
+// projectID, which defaults to the GOOGLE_CLOUD_PROJECT environment
+// variable; without a project, no trace is written. traceSampled, if set,
+// decides whether traces are reported as sampled.
+func newGCPHandler(w io.Writer, opts *slog.HandlerOptions, projectID string,
+	traceSampled func(ctx context.Context) (sampled, ok bool)) *gcpHandler {
+	if projectID == "" {
+		projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	gcpOpts := slog.HandlerOptions{}
+	if opts != nil {
+		gcpOpts = *opts
+	}
+	gcpOpts.ReplaceAttr = chainReplaceAttr(gcpReplacer, gcpOpts.ReplaceAttr)
+	return &gcpHandler{
+		json:         slog.NewJSONHandler(w, &gcpOpts),
+		projectID:    projectID,
+		traceSampled: traceSampled,
+	}
+}
+
+// Enabled determines if the handler is enabled for the given log level.
+func (h *gcpHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.json.Enabled(ctx, level)
+}
+
+// Handle writes the record, with the trace fields first.
+func (h *gcpHandler) Handle(ctx context.Context, record slog.Record) error {
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(h.trace(ctx)...)
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	for _, a := range nestAttrs(h.goas, attrs) {
+		out.AddAttrs(markUserAttr(a))
+	}
+	return h.json.Handle(ctx, out)
+}
+
+// gcpUserValue is the value of a top-level attribute whose key is that of
+// a built-in field, so that gcpReplacer does not rename it.
+type gcpUserValue struct {
+	slog.Value
+}
+
+// markUserAttr wraps the value of a, a top-level attribute, in a
+// gcpUserValue if its key is that of a built-in field gcpReplacer renames.
+// Groups are not passed to ReplaceAttr, so they are left as they are.
+func markUserAttr(a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.MessageKey, slog.LevelKey, slog.SourceKey:
+		if a.Value.Kind() != slog.KindGroup {
+			a.Value = slog.AnyValue(gcpUserValue{a.Value})
+		}
+	}
+	return a
+}
+
+// trace returns the trace fields for the Sentry span in ctx, or for the
+// trace extracted into it with ExtractContext, the span being the one that
+// injected it. The sampling decision is that of Config.TraceSampled, if set
+// and it reports one.
+func (h *gcpHandler) trace(ctx context.Context) []slog.Attr {
+	if h.projectID == "" || ctx == nil {
+		return nil
+	}
+	var traceID, spanID string
+	var sampled bool
+	if span := sentry.SpanFromContext(ctx); span != nil {
+		traceID, spanID, sampled = span.TraceID.String(), span.SpanID.String(), span.Sampled == sentry.SampledTrue
+	} else if _, tid, sid, s, ok := remoteTraceFromContext(ctx); ok {
+		traceID, spanID, sampled = tid, sid, s
+	} else {
+		return nil
+	}
+	if h.traceSampled != nil {
+		if s, ok := h.traceSampled(ctx); ok {
+			sampled = s
+		}
+	}
+	return []slog.Attr{
+		slog.String(gcpTraceKey, fmt.Sprintf("projects/%s/traces/%s", h.projectID, traceID)),
+		slog.String(gcpSpanIDKey, spanID),
+		slog.Bool(gcpTraceSampledKey, sampled),
+	}
+}
+
+// WithAttrs returns a new handler with the given attributes.
+func (h *gcpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{attrs: attrs})
+	return &h2
+}
+
+// WithGroup returns a new handler with the given group name.
+func (h *gcpHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{group: name})
+	return &h2
+}
+
+// gcpReplacer renames the built-in fields to those of the Cloud Logging
+// structured format: the message to "message", the level to a "severity"
+// name and the source to a "logging.googleapis.com/sourceLocation" object.
+// Attributes of the same keys, marked by gcpHandler, keep their keys.
+func gcpReplacer(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	if v, ok := a.Value.Any().(gcpUserValue); ok {
+		return slog.Attr{Key: a.Key, Value: v.Value}
+	}
+	switch a.Key {
+	case slog.MessageKey:
+		a.Key = gcpMessageKey
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			return slog.Attr{Key: gcpSeverityKey, Value: gcpSeverity(level)}
+		}
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			return slog.Group(gcpSourceLocationKey,
+				slog.String("file", src.File),
+				slog.String("line", strconv.Itoa(src.Line)),
+				slog.String("function", src.Function),
+			)
+		}
+	}
+	return a
+}
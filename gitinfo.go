@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"runtime/debug"
+)
+
+// Environment variables consulted for the Git commit and branch, in order of
+// precedence. GIT_COMMIT and GIT_BRANCH can be set explicitly; the others are
+// provided by common CI systems.
+var (
+	gitCommitEnv = []string{"GIT_COMMIT", "GITHUB_SHA", "CI_COMMIT_SHA", "BUILDKITE_COMMIT"}
+	gitBranchEnv = []string{"GIT_BRANCH", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "BUILDKITE_BRANCH"}
+)
+
+// gitInfo describes the code version the process was built from.
+type gitInfo struct {
+	commit string
+	branch string
+}
+
+// readGitInfo returns the Git commit and branch from the environment,
+// falling back to the VCS revision stamped into the binary by the Go
+// toolchain. Either field is empty when unknown.
+func readGitInfo() gitInfo {
+	info := gitInfo{
+		commit: firstEnv(gitCommitEnv),
+		branch: firstEnv(gitBranchEnv),
+	}
+	if info.commit == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range bi.Settings {
+				if s.Key == "vcs.revision" {
+					info.commit = s.Value
+				}
+			}
+		}
+	}
+	return info
+}
+
+// attrs returns the known fields as attributes.
+func (g gitInfo) attrs() []any {
+	var attrs []any
+	if g.commit != "" {
+		attrs = append(attrs, slog.String("git_commit", g.commit))
+	}
+	if g.branch != "" {
+		attrs = append(attrs, slog.String("git_branch", g.branch))
+	}
+	return attrs
+}
+
+func firstEnv(keys []string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
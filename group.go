@@ -0,0 +1,29 @@
+package logger
+
+import "log/slog"
+
+// groupOrAttrs is either a group name or attributes added to a handler,
+// for handlers that build the group structure of records when handling
+// them rather than preformatting it.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// nestAttrs places the attributes of a record inside the groups opened by
+// WithGroup, after the attributes added with WithAttrs within each group,
+// and returns the top-level attributes. Groups left without attributes are
+// dropped.
+func nestAttrs(goas []groupOrAttrs, attrs []slog.Attr) []slog.Attr {
+	for i := len(goas) - 1; i >= 0; i-- {
+		goa := goas[i]
+		if goa.group == "" {
+			attrs = append(goa.attrs[:len(goa.attrs):len(goa.attrs)], attrs...)
+			continue
+		}
+		if len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// periodic runs a function at a fixed interval from a background goroutine.
+type periodic struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// startPeriodic calls fn every interval until the returned periodic is
+// closed.
+func startPeriodic(interval time.Duration, fn func()) *periodic {
+	p := &periodic{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// Close stops the goroutine and waits for it to exit.
+func (p *periodic) Close() error {
+	p.once.Do(func() {
+		close(p.stop)
+		<-p.done
+	})
+	return nil
+}
+
+// startHeartbeat logs an info record showing that the process is still
+// alive through logger every interval, whatever its level, until the
+// returned periodic is closed.
+func startHeartbeat(logger Logger, interval time.Duration, st *stats) *periodic {
+	return startPeriodic(interval, func() {
+		logInternal(logger, slog.LevelInfo, "heartbeat",
+			slog.String(logTypeKey, "heartbeat"),
+			slog.Duration("uptime", st.uptime()),
+			slog.Int64("records", st.records.Load()),
+		)
+	})
+}
+
+// logInternal logs a record of the package itself through logger, passing
+// it to the handler directly so that it is written even if the logger is
+// not enabled for its level. The record has no source.
+func logInternal(logger Logger, level slog.Level, msg string, attrs ...slog.Attr) {
+	record := slog.NewRecord(time.Now(), level, msg, 0)
+	record.AddAttrs(attrs...)
+	_ = logger.Handler().Handle(internalContext(), record)
+}
+
+type internalKey struct{}
+
+// internalContext returns the context used for records emitted by the
+// package itself, which are never sampled out nor counted in the metrics,
+// and are written by the outputs whatever their level.
+func internalContext() context.Context {
+	return context.WithValue(context.Background(), internalKey{}, true)
+}
+
+func isInternal(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	internal, _ := ctx.Value(internalKey{}).(bool)
+	return internal
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"os/user"
+)
+
+// processIdentity describes the OS account the process runs as.
+type processIdentity struct {
+	user string
+	euid int // -1 where the platform has no user IDs
+}
+
+// readProcessIdentity returns the user name and effective UID of the
+// process. The user name is empty when it cannot be looked up, for example
+// in a container whose UID has no passwd entry.
+func readProcessIdentity() processIdentity {
+	id := processIdentity{euid: os.Geteuid()}
+	if u, err := user.Current(); err == nil {
+		id.user = u.Username
+	}
+	return id
+}
+
+// attrs returns the known fields as attributes.
+func (id processIdentity) attrs() []any {
+	var attrs []any
+	if id.user != "" {
+		attrs = append(attrs, slog.String("os_user", id.user))
+	}
+	if id.euid >= 0 {
+		attrs = append(attrs, slog.Int("euid", id.euid))
+	}
+	return attrs
+}
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// indentHandler is a slog.Handler for consoles that writes each record as a
+// key=value line, like the text handler, followed by its groups as an
+// indented tree: one line per group name and per attribute, each nested
+// group indented one level further. A record is written in a single call.
+type indentHandler struct {
+	text   slog.Handler // formats the first line of records
+	enc    *bytes.Buffer
+	opts   slog.HandlerOptions
+	indent string
+	goas   []groupOrAttrs // added with WithGroup and WithAttrs, in order
+	mu     *sync.Mutex    // guards enc and w
+	w      io.Writer
+}
+
+// newIndentHandler returns an indentHandler writing to w, indenting each
+// level of groups by the given number of spaces.
+func newIndentHandler(w io.Writer, opts *slog.HandlerOptions, spaces int) *indentHandler {
+	h := &indentHandler{
+		enc:    &bytes.Buffer{},
+		indent: strings.Repeat(" ", spaces),
+		mu:     &sync.Mutex{},
+		w:      w,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.text = slog.NewTextHandler(h.enc, &h.opts)
+	return h
+}
+
+// Enabled reports whether the level is at or above the configured minimum.
+func (h *indentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.text.Enabled(ctx, level)
+}
+
+// Handle writes the record with its top-level attributes on the first line,
+// and its groups below it.
+func (h *indentHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	var groups []slog.Attr
+	head := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	h.split(nestAttrs(h.goas, attrs), &head, &groups)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enc.Reset()
+	if err := h.text.Handle(ctx, head); err != nil {
+		return err
+	}
+	b := h.enc.Bytes()
+	for _, g := range groups {
+		b = h.appendGroup(b, nil, g, 1)
+	}
+	_, err := h.w.Write(b)
+	return err
+}
+
+// split adds the attributes that are not groups to head, and the groups to
+// groups. Groups without a key are inlined.
+func (h *indentHandler) split(attrs []slog.Attr, head *slog.Record, groups *[]slog.Attr) {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		switch {
+		case a.Value.Kind() != slog.KindGroup:
+			head.AddAttrs(a)
+		case a.Key == "":
+			h.split(a.Value.Group(), head, groups)
+		default:
+			*groups = append(*groups, a)
+		}
+	}
+}
+
+// appendGroup appends the lines of the group g, nested in groups, at the
+// given depth. Groups left without attributes are not appended.
+func (h *indentHandler) appendGroup(b []byte, groups []string, g slog.Attr, depth int) []byte {
+	groups = append(groups[:len(groups):len(groups)], g.Key)
+	members := h.appendMembers(nil, groups, g.Value.Group(), depth+1)
+	if len(members) == 0 {
+		return b
+	}
+	b = append(b, strings.Repeat(h.indent, depth)...)
+	b = append(b, g.Key...)
+	b = append(b, ":\n"...)
+	return append(b, members...)
+}
+
+// appendMembers appends a line per attribute of a group, applying
+// ReplaceAttr to those that are not groups.
+func (h *indentHandler) appendMembers(b []byte, groups []string, attrs []slog.Attr, depth int) []byte {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() != slog.KindGroup && h.opts.ReplaceAttr != nil {
+			a = h.opts.ReplaceAttr(groups, a)
+			a.Value = a.Value.Resolve()
+		}
+		switch {
+		case a.Value.Kind() == slog.KindGroup && a.Key == "":
+			b = h.appendMembers(b, groups, a.Value.Group(), depth)
+		case a.Value.Kind() == slog.KindGroup:
+			b = h.appendGroup(b, groups, a, depth)
+		case a.Key != "":
+			b = append(b, strings.Repeat(h.indent, depth)...)
+			b = append(b, textString(a.Key)...)
+			b = append(b, '=')
+			b = append(b, textString(textValue(a.Value))...)
+			b = append(b, '\n')
+		}
+	}
+	return b
+}
+
+// WithAttrs returns a new handler with the given attributes.
+func (h *indentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{attrs: attrs})
+	return &h2
+}
+
+// WithGroup returns a new handler with the given group name.
+func (h *indentHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{group: name})
+	return &h2
+}
+
+// textValue formats v as the text handler does.
+func textValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format("2006-01-02T15:04:05.000Z07:00")
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case error:
+			return a.Error()
+		case encoding.TextMarshaler:
+			b, err := a.MarshalText()
+			if err != nil {
+				return "!ERROR:" + err.Error()
+			}
+			return string(b)
+		case []byte:
+			return string(a)
+		case time.Duration:
+			return a.String()
+		default:
+			return fmt.Sprintf("%+v", a)
+		}
+	default:
+		return v.String()
+	}
+}
+
+// textString quotes s if it would be ambiguous unquoted in key=value
+// output, as the text handler does.
+func textString(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == '=' || r == '"' || r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
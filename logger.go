@@ -2,9 +2,16 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/getsentry/sentry-go"
@@ -18,6 +25,317 @@ type Config struct {
 	LogLevel     string
 	SentryDSN    string
 	EnableSentry bool
+
+	// BufferedOutput batches writes to stdout in memory instead of writing
+	// each record immediately. Records still in the buffer are lost if the
+	// process crashes before they are flushed.
+	BufferedOutput bool
+	// BufferSize is the size in bytes of the output buffer. Defaults to 4096.
+	BufferSize int
+	// FlushInterval is how often the output buffer is flushed. Defaults to 1s.
+	FlushInterval time.Duration
+
+	// FIFOPath writes records to the named pipe at this path instead of
+	// stdout. Writes never block: while no reader is attached or the pipe is
+	// full, records go to FIFOFallback.
+	FIFOPath string
+	// FIFOFallback receives records the FIFO cannot take: "stdout" (the
+	// default) or "discard".
+	FIFOFallback string
+
+	// RedactPaths lists dot-separated paths whose values are replaced with
+	// "[REDACTED]", such as "password" or "config.db.password".
+	RedactPaths []string
+	// RedactKeys lists keys whose values are redacted wherever they appear,
+	// at the top level or nested in groups and values. Matching ignores case.
+	RedactKeys []string
+	// RedactPatterns lists regular expressions whose matches are masked in
+	// every string value and in messages.
+	RedactPatterns []string
+	// RedactMaxDepth bounds how deep redaction descends into attribute
+	// values, an attribute of the record being at depth 1. Defaults to 8.
+	RedactMaxDepth int
+	// RedactPII masks personal data detected by heuristics in string values
+	// and messages: "email", "card" (payment card numbers passing the Luhn
+	// check), "ssn" (US Social Security numbers), "phone", or "all".
+	RedactPII []string
+	// RedactPolicyFile is the path of a JSON file with additional redaction
+	// rules, loaded once when the logger is created.
+	RedactPolicyFile string
+
+	// SentryDryRun logs the events that would be sent to Sentry as JSON
+	// records on stderr instead of sending them. It enables the Sentry
+	// integration with EnableSentry alone, without a SentryDSN.
+	SentryDryRun bool
+	// SentryFields are attributes attached as extras to every Sentry event,
+	// and never written to the other sinks. Attributes of the record with
+	// the same keys take precedence.
+	SentryFields map[string]any
+	// SentryMessageAttrs lists attribute keys whose values are appended to
+	// the Sentry event message as " key=value", in the listed order.
+	SentryMessageAttrs []string
+	// SentryBreadcrumbs records logged below the Sentry level with a context
+	// carrying a Sentry hub, see WithSentryHub, as breadcrumbs on that hub.
+	// They are attached to the next event captured for the same request.
+	SentryBreadcrumbs bool
+	// SentryMaxBreadcrumbs bounds the breadcrumbs kept per hub. Defaults to
+	// 30; Sentry caps it at 100.
+	SentryMaxBreadcrumbs int
+	// SentryCanceledContexts selects how records logged with a canceled or
+	// expired context are sent to Sentry: "async" (the default) sends them
+	// from a background goroutine without waiting, "skip" does not send them.
+	SentryCanceledContexts string
+	// SentryRouteAttr is the key of the attribute, such as "tenant", whose
+	// value routes records to the Sentry project of SentryRoutes. Keys of
+	// grouped attributes are joined by dots.
+	SentryRouteAttr string
+	// SentryRoutes maps values of the SentryRouteAttr attribute to the DSN
+	// of the Sentry project receiving their events. Records without the
+	// attribute, or with a value not listed, go to SentryDSN. At most 32
+	// routes are allowed.
+	SentryRoutes map[string]string
+	// SentryQuietHours is a daily "HH:MM-HH:MM" window, such as
+	// "22:00-07:00", during which only error and fatal records are sent to
+	// Sentry; warnings are recorded as breadcrumbs on the hub of their
+	// context instead. Other sinks are not affected.
+	SentryQuietHours string
+	// SentryQuietTimezone is the IANA time zone of SentryQuietHours, such
+	// as "Europe/Berlin". Defaults to the local time zone of the process.
+	SentryQuietTimezone string
+	// StackTraceDepth is the maximum number of frames in the stack traces
+	// attached to Sentry events. Defaults to 32.
+	StackTraceDepth int
+
+	// Format selects the output encoding: "json" (the default), "text"
+	// (slog's key=value format, for consoles), "csv", "msgpack" or "gcp"
+	// (JSON in the Google Cloud Logging structured format).
+	Format string
+	// JSONRootKey, when set, wraps each record of the "json" format in an
+	// object under that key, such as {"log":{...}}. Other formats are not
+	// affected.
+	JSONRootKey string
+	// StrictNDJSON guarantees that each record of the "json" and "gcp"
+	// formats is written as one valid JSON line ending in a single newline,
+	// escaping stray line breaks in values; records that are still invalid
+	// are replaced with an error record embedding them.
+	StrictNDJSON bool
+	// FlushEachRecord flushes BufferedOutput after each record in
+	// StrictNDJSON mode, so that streaming consumers see whole records as
+	// soon as they are logged.
+	FlushEachRecord bool
+	// TextIndent, when positive, writes the groups of records in the "text"
+	// format as an indented tree below the record, with that many spaces
+	// per level, rather than as dotted keys on the same line.
+	TextIndent int
+	// AutoFormat selects the format when Format is empty: "text" if records
+	// are written to a terminal, stdout or an Output *os.File, "json"
+	// otherwise.
+	AutoFormat bool
+	// SeverityScheme adds a severity field next to the level, mapped from
+	// it: "syslog" (RFC 5424 numbers, as "severity"), "otel"
+	// (OpenTelemetry severity numbers, as "severity_number") or "gcp"
+	// (Google Cloud Logging severity names, as "severity"). The "gcp"
+	// format always writes the latter, and New fails with another scheme.
+	SeverityScheme string
+	// FloatPrecision, when positive, writes float attributes with that many
+	// decimals. By default floats are written as slog writes them.
+	FloatPrecision int
+	// FloatNoExponent writes float attributes without scientific notation,
+	// in full. With either float option, NaN and infinities are written as
+	// the strings "NaN", "+Inf" and "-Inf".
+	FloatNoExponent bool
+	// GCPProjectID is the Google Cloud project that traces are qualified by
+	// in the "gcp" format. Defaults to the GOOGLE_CLOUD_PROJECT environment
+	// variable.
+	GCPProjectID string
+	// CSVColumns lists the CSV columns in order. Each names a built-in field
+	// ("time", "level", "msg", "source") or an attribute key, with group
+	// names joined by dots. Defaults to time, level and msg.
+	CSVColumns []string
+	// CSVHeader writes a header row with the column names when the logger
+	// is created.
+	CSVHeader bool
+	// CSVPackAttrs packs attributes without a column into a trailing "attrs"
+	// column as a JSON object instead of dropping them.
+	CSVPackAttrs bool
+
+	// IncludeGitInfo attaches the Git commit and branch the process was
+	// built from as "git_commit" and "git_branch" attributes, and uses the
+	// commit as the Sentry release unless SENTRY_RELEASE is set.
+	IncludeGitInfo bool
+
+	// IncludeLocalTime writes the "time" field in UTC and adds a
+	// "local_time" field with the time in the local zone.
+	IncludeLocalTime bool
+
+	// IncludeProcessIdentity attaches the OS user name and effective user ID
+	// the process runs as, as "os_user" and "euid" attributes.
+	IncludeProcessIdentity bool
+
+	// CallerSkip is the number of frames above the call to the logging
+	// method at which the source of records is reported, for loggers only
+	// ever called through wrapper functions. See also WithCallerSkip.
+	CallerSkip int
+	// DeferSource postpones the stack walk that applies CallerSkip until a
+	// record has been sampled, so records dropped by sampling do not pay
+	// for it. Nothing else is deferred, and it only makes a difference with
+	// EscalationRules or Metrics, which make sampling happen after them;
+	// otherwise records are sampled before the walk anyway. Enrichers,
+	// escalation rules and metrics then see the PC of the call to the
+	// logging method, before the skip applies.
+	DeferSource bool
+
+	// IncludeSize adds a "size" field with the approximate size in bytes
+	// of each record as written, in the json, gcp and text formats, unless
+	// text groups are indented.
+	IncludeSize bool
+
+	// IntegrityChain adds a "chain" field to each record written to the
+	// output, with a checksum chaining it to the previous record, so that
+	// modified, inserted or removed records can be detected by VerifyChain.
+	// It applies to the json, gcp and text formats, unless text groups are
+	// indented.
+	IntegrityChain bool
+	// IntegrityKey is the key of the HMAC-SHA256 checksums of the chain.
+	// Without a key, checksums are plain SHA-256 hashes, which detect
+	// accidental damage but not tampering.
+	IntegrityKey []byte
+
+	// IncludeSequence adds a top-level "seq" field numbering the records
+	// emitted by the logger and the loggers derived from it, from 1, so
+	// consumers can detect dropped or reordered records.
+	IncludeSequence bool
+	// IncludeRecordID adds a top-level "record_id" field with a random ID
+	// to every record not logged with a RecordID, for CausedBy links.
+	IncludeRecordID bool
+	// DefaultRetention, when positive, adds a "retention" hint to every
+	// record, telling downstream systems how long to keep it, such as "30d".
+	// Loggers and records override it with WithRetention and Retention.
+	DefaultRetention time.Duration
+
+	// MessageOnly drops every attribute, and the source location, so that
+	// records carry only their time, level and message, in every sink
+	// including Sentry. It overrides all attribute-related options.
+	MessageOnly bool
+
+	// MaxAttrs caps the number of attributes emitted per record, counting
+	// those added with With. Extra attributes are dropped and counted in a
+	// top-level "dropped_attrs" field. Zero means unlimited.
+	MaxAttrs int
+
+	// LokiURL is the base URL of a Grafana Loki instance, such as
+	// "http://loki:3100". When set, records are also pushed to Loki.
+	LokiURL string
+	// LokiLabels are static labels attached to every stream pushed to Loki.
+	LokiLabels map[string]string
+	// LokiLabelAttrs lists top-level attribute keys whose values become Loki
+	// labels. At most 8 are allowed; keep them to low-cardinality values.
+	LokiLabelAttrs []string
+	// LokiBatchSize is the maximum number of records per push. Defaults to
+	// BatchSize.
+	LokiBatchSize int
+	// LokiBatchWait is the maximum time a record waits before being pushed.
+	// Defaults to BatchWait.
+	LokiBatchWait time.Duration
+
+	// AzureConnectionString is the connection string of an Azure Monitor
+	// Application Insights resource, such as
+	// "InstrumentationKey=...;IngestionEndpoint=https://...". When set,
+	// records are also sent to Application Insights, errors as exceptions
+	// and other records as traces.
+	AzureConnectionString string
+	// AzureBatchSize is the maximum number of records per request. Defaults
+	// to BatchSize.
+	AzureBatchSize int
+	// AzureBatchWait is the maximum time a record waits before being sent.
+	// Defaults to BatchWait.
+	AzureBatchWait time.Duration
+
+	// BatchSize is the maximum number of records a network sink, Loki or
+	// Application Insights, sends per request, unless set for the sink.
+	// Defaults to 100.
+	BatchSize int
+	// BatchWait is the maximum time a record waits before a network sink
+	// sends it, unless set for the sink. Defaults to 1s.
+	BatchWait time.Duration
+
+	// SampleRate is the fraction of debug and info records kept, between 0
+	// and 1. Warnings and errors are always kept. Zero disables sampling.
+	SampleRate float64
+	// RateLimit caps the debug and info records kept per second, after
+	// sampling, with a token bucket. Warnings and errors are never limited.
+	// Zero disables the limit.
+	RateLimit float64
+	// RateBurst is the number of records that may be kept at once before
+	// RateLimit applies, the capacity of the token bucket. Defaults to one
+	// second's worth of records.
+	RateBurst int
+	// SampleByTrace keeps every record logged with a context whose trace is
+	// sampled, applying SampleRate only to the others.
+	SampleByTrace bool
+	// TraceSampled reports whether ctx carries a trace and whether it is
+	// sampled. Defaults to reading the Sentry span from ctx; set it to read
+	// OpenTelemetry span contexts instead.
+	TraceSampled func(ctx context.Context) (sampled, ok bool)
+
+	// BaggageKeys lists the baggage entries, attached to a context with
+	// WithBaggage, that are added to records. Other entries are ignored.
+	BaggageKeys []string
+
+	// Enrichers add attributes to every record, in order, after context
+	// attributes and before MaxAttrs and redaction apply.
+	Enrichers []Enricher
+	// EscalationRules raise the level of records whose attributes match
+	// them, after the enrichers ran, so that the escalated level is the one
+	// written and sent to Sentry.
+	EscalationRules []EscalationRule
+
+	// Metrics, when set, counts every record the logger emits at its level,
+	// after escalation and before sampling.
+	Metrics Metrics
+	// MetricsLabels lists the attributes whose values label the counts,
+	// with the keys of enclosing groups joined by dots, such as "service"
+	// or "http.method". Keep to attributes of few distinct values: each
+	// combination is a separate counter.
+	MetricsLabels []string
+	// MetricsOnly suppresses all log output, to stdout or the configured
+	// Output, Loki and Application Insights, while Metrics keeps counting
+	// records. Sentry still follows EnableSentry. It requires Metrics.
+	MetricsOnly bool
+
+	// EmitSchema writes a schema descriptor record, listing the fields the
+	// logger adds to records, when the logger is created. The record has a
+	// "log_type" attribute set to "schema".
+	EmitSchema bool
+
+	// PropagatePanics lets panics raised while handling a record, by an
+	// enricher, a LogValuer or a handler, crash the logging goroutine.
+	// By default they are recovered and reported on stderr.
+	PropagatePanics bool
+
+	// ExitFunc is called by Fatal to exit the process, instead of os.Exit.
+	// Tests set it to observe fatal errors without exiting.
+	ExitFunc func(code int)
+
+	// HeartbeatInterval, when positive, logs an info "heartbeat" record at
+	// that interval from a background goroutine until the logger is closed,
+	// whatever LogLevel. Heartbeats are not counted in the Metrics.
+	HeartbeatInterval time.Duration
+	// RuntimeStatsInterval, when positive, logs a debug "runtime stats"
+	// record with the number of goroutines and memory statistics at that
+	// interval from a background goroutine until the logger is closed.
+	RuntimeStatsInterval time.Duration
+	// SummaryOnClose logs an info "log summary" record when the logger is
+	// closed, with the number of records emitted per level and in total,
+	// the number dropped by sampling and rate limiting, and the uptime. It
+	// is written whatever LogLevel, before the outputs are flushed and
+	// closed, and is not counted in the Metrics.
+	SummaryOnClose bool
+
+	// Output receives the records instead of stdout, such as a file or, in
+	// tests, a buffer. FIFOPath takes precedence. It is not closed with the
+	// logger.
+	Output io.Writer
 }
 
 // New initializes a new Logger based on the provided configuration.
@@ -38,38 +356,331 @@ func New(config Config) (Logger, error) {
 
 	opts := &slog.HandlerOptions{
 		Level:     level,
-		AddSource: true,
+		AddSource: !config.MessageOnly,
+	}
+	severity, err := newSeverityScheme(config.SeverityScheme)
+	if err != nil {
+		return nil, err
+	}
+	gcp := outputFormat(config) == "gcp"
+	if gcp && severity != nil && config.SeverityScheme != "gcp" {
+		return nil, fmt.Errorf("severity scheme %q is not supported with the gcp format, which writes its own severity", config.SeverityScheme)
+	}
+	var replacers []replaceAttrFunc
+	if severity != nil && !gcp {
+		replacers = append(replacers, severity.replacer())
+	}
+	replacers = append(replacers, levelReplacer, floatReplacer(config.FloatPrecision, config.FloatNoExponent))
+	if config.IncludeLocalTime {
+		replacers = append(replacers, localTimeReplacer)
+	}
+	opts.ReplaceAttr = chainReplaceAttr(replacers...)
+
+	rules := redactRules{
+		Keys:     config.RedactKeys,
+		Patterns: config.RedactPatterns,
+		Paths:    config.RedactPaths,
+		MaxDepth: config.RedactMaxDepth,
+		PII:      config.RedactPII,
+	}
+	if config.RedactPolicyFile != "" {
+		policy, err := loadRedactRules(config.RedactPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = rules.merge(policy)
+	}
+	redactor, err := newRedactor(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the rest of the configuration before outputs are opened and
+	// goroutines started, which must be released on any later error
+	if config.MetricsOnly && config.Metrics == nil {
+		return nil, errors.New("metrics-only mode requires Metrics")
+	}
+	skipCanceled, err := canceledSentryMode(config.SentryCanceledContexts)
+	if err != nil {
+		return nil, err
+	}
+	quiet, err := newQuietHours(config.SentryQuietHours, config.SentryQuietTimezone)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSentryRoutes(config.SentryRouteAttr, config.SentryRoutes); err != nil {
+		return nil, err
+	}
+	escalations, err := newEscalationRules(config.EscalationRules)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &resources{}
+
+	var outputHandler slog.Handler
+	if config.MetricsOnly {
+		outputHandler = discardHandler{level: level}
+	} else {
+		outputHandler, err = newOutputs(config, opts, res)
+		if err != nil {
+			res.close()
+			return nil, err
+		}
 	}
 
-	jsonHandler := slog.NewJSONHandler(os.Stdout, opts)
 	sentryHandler := &sentryHandler{
-		next:        nil,
-		minLogLevel: slog.LevelWarn,
+		next:         nil,
+		minLogLevel:  slog.LevelWarn,
+		messageAttrs: config.SentryMessageAttrs,
+		breadcrumbs:  config.SentryBreadcrumbs,
+		skipCanceled: skipCanceled,
+		quiet:        quiet,
+		detached:     &atomic.Int32{},
+	}
+	if !config.MessageOnly {
+		sentryHandler.attrs = sentryFields(config.SentryFields)
+		if redactor != nil {
+			sentryHandler.attrs = redactor.attrs(nil, sentryHandler.attrs)
+		}
 	}
 
 	combinedHandler := &combinedHandler{
-		jsonHandler:   jsonHandler,
+		outputHandler: outputHandler,
 		sentryHandler: sentryHandler,
 	}
 
-	var logger *slog.Logger
-	if config.EnableSentry && config.SentryDSN != "" {
-		if err := sentry.Init(sentry.ClientOptions{
+	var git gitInfo
+	if config.IncludeGitInfo {
+		git = readGitInfo()
+	}
+
+	var handler slog.Handler
+	if config.EnableSentry && (config.SentryDSN != "" || config.SentryDryRun) {
+		options := sentry.ClientOptions{
 			Dsn:              config.SentryDSN,
 			EnableTracing:    true,
 			TracesSampleRate: 0.05,
-		}); err != nil {
+			MaxBreadcrumbs:   config.SentryMaxBreadcrumbs,
+			AttachStacktrace: true,
+			BeforeSend:       stackTraceTrimmer(config.StackTraceDepth),
+		}
+		if config.SentryDryRun {
+			options.Transport = newDryRunTransport(os.Stderr)
+		}
+		if git.commit != "" && os.Getenv("SENTRY_RELEASE") == "" {
+			options.Release = git.commit
+		}
+		if err := sentry.Init(options); err != nil {
+			res.close()
 			return nil, fmt.Errorf("sentry.Init failed: %s", err)
 		}
+		router, err := newSentryRouter(config.SentryRouteAttr, config.SentryRoutes, options)
+		if err != nil {
+			res.close()
+			return nil, err
+		}
+		if router != nil {
+			sentryHandler.router = router
+			res.add(router)
+		}
 		defer sentry.Flush(2 * time.Second)
-		logger = slog.New(combinedHandler)
+		res.add(closerFunc(func() error {
+			sentry.Flush(2 * time.Second)
+			return nil
+		}))
+		handler = combinedHandler
 	} else {
-		logger = slog.New(jsonHandler)
+		handler = outputHandler
+	}
+
+	if config.EmitSchema {
+		if err := emitSchema(outputHandler, config); err != nil {
+			res.close()
+			return nil, fmt.Errorf("writing schema descriptor: %w", err)
+		}
+	}
+
+	effective := effectiveConfig(config)
+	st := newStats()
+	sinks := &sinkSet{}
+	res.add(sinks)
+	logger := slog.New(&rootHandler{
+		next:      handler,
+		res:       res,
+		stats:     st,
+		level:     level,
+		redactor:  redactor,
+		sampler:   newSampler(config),
+		sinks:     sinks,
+		sinkCache: &atomic.Pointer[sinkCache]{},
+		maxAttrs:  config.MaxAttrs,
+
+		callerSkip:    max(config.CallerSkip, 0),
+		deferSource:   config.DeferSource,
+		messageOnly:   config.MessageOnly,
+		sequence:      config.IncludeSequence,
+		recordID:      config.IncludeRecordID,
+		config:        &effective,
+		retention:     formatRetention(config.DefaultRetention),
+		baggageKeys:   config.BaggageKeys,
+		enrichers:     config.Enrichers,
+		escalations:   escalations,
+		metrics:       config.Metrics,
+		metricsLabels: config.MetricsLabels,
+		exit:          config.ExitFunc,
+
+		propagatePanics: config.PropagatePanics,
+	})
+	attrs := git.attrs()
+	if config.IncludeProcessIdentity {
+		attrs = append(attrs, readProcessIdentity().attrs()...)
+	}
+	if len(attrs) > 0 {
+		logger = logger.With(attrs...)
+	}
+
+	if config.HeartbeatInterval > 0 {
+		res.add(startHeartbeat(logger, config.HeartbeatInterval, st))
+	}
+	if config.RuntimeStatsInterval > 0 {
+		res.add(startRuntimeStats(logger, config.RuntimeStatsInterval))
+	}
+	if config.SummaryOnClose {
+		res.add(closerFunc(func() error {
+			logSummary(logger, st)
+			return nil
+		}))
 	}
 
 	return logger, nil
 }
 
+// newOutputs returns the handler writing records to the output, as well as
+// to Loki and Application Insights if configured, registering what must be
+// closed in res.
+func newOutputs(config Config, opts *slog.HandlerOptions, res *resources) (slog.Handler, error) {
+	var out io.Writer = os.Stdout
+	if config.Output != nil {
+		out = config.Output
+	}
+	if config.FIFOPath != "" {
+		fallback, err := fifoFallback(config.FIFOFallback)
+		if err != nil {
+			return nil, err
+		}
+		fifo, err := newFIFOWriter(config.FIFOPath, fallback)
+		if err != nil {
+			return nil, err
+		}
+		res.add(fifo)
+		out = fifo
+	}
+	var flush func() error
+	if config.BufferedOutput {
+		bw := newBufferedWriter(out, config.BufferSize, config.FlushInterval)
+		res.add(bw)
+		out = bw
+		flush = bw.Flush
+	}
+	// Records are wrapped under the root key last, so that the size and
+	// checksum fields are inside the root object
+	if rw := newRootKeyWriter(out, outputFormat(config), config.JSONRootKey); rw != nil {
+		out = rw
+	}
+	if config.IntegrityChain {
+		cw, err := newChainWriter(out, config)
+		if err != nil {
+			return nil, err
+		}
+		out = cw
+	}
+	if config.IncludeSize {
+		if sw := sizeWriterFor(out, config); sw != nil {
+			out = sw
+		}
+	}
+	if config.StrictNDJSON {
+		if !config.FlushEachRecord {
+			flush = nil
+		}
+		nw, err := newNDJSONWriter(out, config, flush)
+		if err != nil {
+			return nil, err
+		}
+		out = nw
+	}
+
+	outputHandler, err := newOutputHandler(config, out, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.LokiURL != "" {
+		lokiHandler, err := newLokiHandler(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		res.add(lokiHandler)
+		outputHandler = &multiHandler{handlers: []slog.Handler{outputHandler, lokiHandler}}
+	}
+	if config.AzureConnectionString != "" {
+		azureHandler, err := newAzureHandler(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		res.add(azureHandler)
+		if m, ok := outputHandler.(*multiHandler); ok {
+			m.handlers = append(m.handlers, azureHandler)
+		} else {
+			outputHandler = &multiHandler{handlers: []slog.Handler{outputHandler, azureHandler}}
+		}
+	}
+	return outputHandler, nil
+}
+
+// newOutputHandler returns the handler that encodes records in the
+// configured format and writes them to out.
+func newOutputHandler(config Config, out io.Writer, opts *slog.HandlerOptions) (slog.Handler, error) {
+	switch format := outputFormat(config); format {
+	case "", "json":
+		return slog.NewJSONHandler(out, opts), nil
+	case "text":
+		if config.TextIndent > 0 {
+			return newIndentHandler(out, opts, config.TextIndent), nil
+		}
+		return slog.NewTextHandler(out, opts), nil
+	case "csv":
+		return newCSVHandler(out, opts, config.CSVColumns, config.CSVHeader, config.CSVPackAttrs)
+	case "msgpack":
+		return newMsgpackHandler(out, opts), nil
+	case "gcp":
+		return newGCPHandler(out, opts, config.GCPProjectID, config.TraceSampled), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q", format)
+	}
+}
+
+// recordSource resolves the source location of a record from its PC.
+func recordSource(record slog.Record) *slog.Source {
+	if record.PC == 0 {
+		return nil
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+	return &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+}
+
+// Close flushes buffered output and releases the resources held by a logger
+// created with New. Loggers derived with With or WithGroup share the
+// resources of the logger they were derived from, so closing any of them
+// closes all of them. Close is safe to call more than once.
+func Close(l Logger) error {
+	if c, ok := l.Handler().(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // NewTag initializes a new Logger with a specific tag added to its context.
 func NewTag(config Config, tag string) (Logger, error) {
 	logger, err := New(config)
@@ -83,36 +694,311 @@ func NewTag(config Config, tag string) (Logger, error) {
 	return logger, nil
 }
 
-// sentryHandler is a custom slog.Handler that sends log records to Sentry.
-type sentryHandler struct {
-	next        slog.Handler
-	minLogLevel slog.Level
+// rootHandler is the outermost handler of every logger built by New. It owns
+// the resources shared by the logger and all loggers derived from it.
+type rootHandler struct {
+	next     slog.Handler
+	res      *resources
+	stats    *stats
+	level    slog.Level
+	redactor *redactor
+	sampler  *sampler
+	groups   []string
+
+	sinks     *sinkSet                   // attached with AddSink
+	goas      []groupOrAttrs             // passed to next, for the sinks
+	sinkCache *atomic.Pointer[sinkCache] // sinks derived with goas
+
+	// top is next before the first group was opened, or nil, which records
+	// carrying top-level fields are passed to with their attributes nested
+	// in the groups of goas[topGoas:]
+	top     slog.Handler
+	topGoas int
+
+	maxAttrs     int
+	numAttrs     int // attributes added with WithAttrs
+	droppedAttrs int // attributes dropped by WithAttrs
+
+	callerSkip    int  // frames to skip when reporting the source
+	deferSource   bool // apply callerSkip after sampling
+	messageOnly   bool
+	sequence      bool   // add the sequence number of records
+	retention     string // retention hint of records, if any
+	recordID      bool   // add a record ID to records without one
+	withRecordID  string // record ID set with WithAttrs, if any
+	baggageKeys   []string
+	enrichers     []Enricher
+	escalations   []escalationRule
+	config        *Config // effective configuration, for ExportConfig
+	metrics       Metrics
+	metricsLabels []string
+	exit          func(code int)    // called by Fatal, if set
+	sentryTags    map[string]string // set with WithSentryTag
+
+	propagatePanics bool
 }
 
-// Handle processes the log record and sends it to Sentry if the log level is high enough.
-func (h *sentryHandler) Handle(ctx context.Context, record slog.Record) error {
-	// Check if the record's log level meets the minimum level to send to Sentry
-	if record.Level < h.minLogLevel {
-		if h.next != nil {
-			return h.next.Handle(ctx, record) // Pass to the next handler without sending to Sentry
+// Handle samples the record, merges context attributes and registered
+// context values into it, runs the enrichers, applies the escalation rules,
+// drops the attributes that are not enabled at the logger's level and caps
+// the remaining ones, counts the record in the metrics, redacts it, numbers
+// it and adds its ID if so configured, and passes it to the next handler.
+// With escalation rules or metrics, the record is sampled only after being
+// counted. The caller skip is applied first, or after sampling if deferred.
+// Sentry tags are taken off the record and passed on through the context,
+// and retention hints are replaced by a single one, added last. The level
+// before escalation, the number of attributes dropped, the sequence number,
+// the record ID and the retention hint are added at the top level, outside
+// the groups opened with WithGroup.
+// In message-only mode, all attributes are dropped instead. The record is
+// also passed to the sinks attached with AddSink, and only to them if the
+// next handler is not enabled for its level. Panics are recovered and
+// reported on stderr, unless configured to propagate.
+func (h *rootHandler) Handle(ctx context.Context, record slog.Record) (err error) {
+	if !h.propagatePanics {
+		defer h.recoverPanic(record, &err)
+	}
+	lateSampling := len(h.escalations) > 0 || h.metrics != nil
+	if !lateSampling && !h.sampler.keep(ctx, record.Level) {
+		h.stats.dropped.Add(1)
+		return nil
+	}
+	if h.callerSkip > 0 && record.PC != 0 && !h.deferSource {
+		record.PC = skipCallers(record.PC, h.callerSkip)
+	}
+	retention, id := h.retention, h.withRecordID
+	var escalatedFrom string
+	var dropped int
+	if h.messageOnly {
+		record = slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	} else {
+		record = mergeContext(ctx, record, h.baggageKeys)
+		record = registeredContextAttrs(ctx, record)
+		record = enrich(ctx, record, h.enrichers)
+		if len(h.escalations) > 0 {
+			record, escalatedFrom = h.escalate(record)
+		}
+		var tags map[string]string
+		record, tags = sentryTagsRecord(record, h.sentryTags)
+		record, retention = retentionRecord(record, retention)
+		record, id = recordIDRecord(record, id)
+		ctx = withSentryTags(ctx, tags)
+		record = filterLeveledRecord(record, h.level)
+		if h.maxAttrs > 0 {
+			record, dropped = h.capAttrs(record)
+		}
+	}
+	if h.metrics != nil && !isInternal(ctx) {
+		h.count(ctx, record)
+	}
+	if lateSampling && !h.sampler.keep(ctx, record.Level) {
+		h.stats.dropped.Add(1)
+		return nil
+	}
+	if h.callerSkip > 0 && record.PC != 0 && h.deferSource {
+		record.PC = skipCallers(record.PC, h.callerSkip)
+	}
+	if h.redactor != nil {
+		record = h.redactor.record(h.groups, record)
+	}
+	seq := h.stats.records.Add(1)
+	h.stats.levels[levelIndex(record.Level)].Add(1)
+	var fields []slog.Attr // added at the top level, outside any group
+	if escalatedFrom != "" {
+		fields = append(fields, slog.String(escalatedFromKey, escalatedFrom))
+	}
+	if dropped > 0 {
+		fields = append(fields, slog.Int(droppedAttrsKey, dropped))
+	}
+	if h.sequence && !h.messageOnly {
+		fields = append(fields, slog.Int64(sequenceKey, seq))
+	}
+	if id == "" && h.recordID && !h.messageOnly {
+		id = NewRecordID()
+	}
+	if id != "" && !h.messageOnly {
+		fields = append(fields, slog.String(recordIDKey, id))
+	}
+	if retention != "" && !h.messageOnly {
+		fields = append(fields, slog.String(retentionKey, retention))
+	}
+	next, top := h.next, len(fields) > 0 && h.top != nil
+	if top {
+		next, record = h.top, h.ungroup(record)
+	} else if len(fields) > 0 {
+		record = record.Clone()
+	}
+	record.AddAttrs(fields...)
+	if h.sinks.sinks.Load() == nil {
+		return next.Handle(ctx, record)
+	}
+	if !next.Enabled(ctx, record.Level) && !isInternal(ctx) {
+		return h.handleSinks(ctx, record, top)
+	}
+	err = next.Handle(ctx, record.Clone())
+	return errors.Join(err, h.handleSinks(ctx, record, top))
+}
+
+// ungroup returns the record with its attributes nested in the groups
+// opened with WithGroup, and the attributes added after the first of them,
+// for top-level fields to be added to it before it is passed to h.top.
+func (h *rootHandler) ungroup(record slog.Record) slog.Record {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(nestAttrs(h.goas[h.topGoas:], attrs)...)
+	return out
+}
+
+// Enabled determines if the handler, or any sink attached to it, is enabled
+// for the given log level.
+func (h *rootHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level) || h.sinksEnabled(ctx, level)
+}
+
+// WithAttrs returns a new root handler with the given attributes.
+func (h *rootHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	attrs, skip := splitCallerSkip(attrs)
+	h2 := *h
+	h2.callerSkip += skip
+	if h.messageOnly {
+		return &h2
+	}
+	attrs, _ = filterLeveled(attrs, h.level)
+	attrs, h2.sentryTags = splitSentryTags(attrs, h.sentryTags)
+	attrs, h2.retention = splitRetention(attrs, h.retention)
+	attrs, h2.withRecordID = splitRecordID(attrs, h.withRecordID)
+	if h.maxAttrs > 0 {
+		if remaining := max(h.maxAttrs-h.numAttrs, 0); len(attrs) > remaining {
+			h2.droppedAttrs += len(attrs) - remaining
+			attrs = attrs[:remaining]
 		}
+		h2.numAttrs += len(attrs)
 	}
+	if h.redactor != nil {
+		attrs = h.redactor.attrs(h.groups, attrs)
+	}
+	h2.next = h.next.WithAttrs(attrs)
+	if len(attrs) > 0 {
+		h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{attrs: attrs})
+		h2.sinkCache = &atomic.Pointer[sinkCache]{}
+	}
+	return &h2
+}
+
+// WithGroup returns a new root handler with the given group name.
+func (h *rootHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	if name != "" && h.top == nil {
+		h2.top, h2.topGoas = h.next, len(h.goas)
+	}
+	if name != "" {
+		h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{group: name})
+		h2.sinkCache = &atomic.Pointer[sinkCache]{}
+	}
+	return &h2
+}
+
+// droppedAttrsKey is the attribute holding the number of attributes dropped
+// from a record by Config.MaxAttrs.
+const droppedAttrsKey = "dropped_attrs"
 
-	// Prepare attributes as context for Sentry
-	attrs := map[string]interface{}{}
+// capAttrs drops the record attributes beyond the handler's budget and
+// returns the record with the number of attributes dropped, including
+// those dropped by With, for the "dropped_attrs" field.
+func (h *rootHandler) capAttrs(record slog.Record) (slog.Record, int) {
+	remaining := max(h.maxAttrs-h.numAttrs, 0)
+	if record.NumAttrs() <= remaining && h.droppedAttrs == 0 {
+		return record, 0
+	}
+
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	dropped := h.droppedAttrs
 	record.Attrs(func(a slog.Attr) bool {
-		attrs[a.Key] = a.Value.Any()
+		if remaining > 0 {
+			out.AddAttrs(a)
+			remaining--
+		} else {
+			dropped++
+		}
 		return true
 	})
+	return out, dropped
+}
+
+// Close releases the resources shared by the handler and its derivatives.
+func (h *rootHandler) Close() error {
+	return h.res.close()
+}
+
+// resources tracks everything that must be released when a logger is closed.
+type resources struct {
+	mu      sync.Mutex
+	closers []io.Closer
+	once    sync.Once
+	err     error
+}
 
-	// Capture log message as a Sentry event
-	sentry.WithScope(func(scope *sentry.Scope) {
-		for k, v := range attrs {
-			scope.SetExtra(k, v)
+// add registers a closer to be called when the resources are closed.
+func (r *resources) add(c io.Closer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closers = append(r.closers, c)
+}
+
+// close calls the registered closers once, in reverse order of registration.
+func (r *resources) close() error {
+	r.once.Do(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		var errs []error
+		for i := len(r.closers) - 1; i >= 0; i-- {
+			if err := r.closers[i].Close(); err != nil {
+				errs = append(errs, err)
+			}
 		}
-		scope.SetLevel(slogToSentryLevel(record.Level)) // Map slog level to Sentry level
-		sentry.CaptureMessage(record.Message)
+		r.err = errors.Join(errs...)
 	})
+	return r.err
+}
+
+// closerFunc adapts a function to the io.Closer interface.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// sentryHandler is a custom slog.Handler that sends log records to Sentry.
+type sentryHandler struct {
+	next         slog.Handler
+	minLogLevel  slog.Level
+	messageAttrs []string
+	breadcrumbs  bool
+	skipCanceled bool          // skip records logged with a done context
+	quiet        *quietHours   // hours warnings are breadcrumbs, or nil
+	detached     *atomic.Int32 // captures in flight for done contexts
+	router       *sentryRouter // clients of the other projects, or nil
+	attrs        []slog.Attr   // added with WithAttrs, keys qualified by group
+	prefix       string        // group names joined by dots, with a trailing dot
+}
+
+// Handle processes the log record and sends it to Sentry if the log level is high enough.
+func (h *sentryHandler) Handle(ctx context.Context, record slog.Record) error {
+	// Only records at or above the minimum level are sent to Sentry; lower
+	// ones may be kept as breadcrumbs for the request, as are warnings
+	// during quiet hours
+	switch {
+	case record.Level >= h.minLogLevel && h.quiet.quieted(record.Level, record.Time):
+		h.addBreadcrumb(ctx, record)
+	case record.Level >= h.minLogLevel:
+		h.capture(ctx, record)
+	case h.breadcrumbs:
+		h.addBreadcrumb(ctx, record)
+	}
 
 	if h.next != nil {
 		return h.next.Handle(ctx, record) // Optionally pass to the next handler
@@ -120,6 +1006,139 @@ func (h *sentryHandler) Handle(ctx context.Context, record slog.Record) error {
 	return nil
 }
 
+// capture sends the record to Sentry as a message event, through the hub of
+// ctx if it has one. Attributes are set as extras in a single pass over the
+// record, which also collects the errors used for deduplication and the
+// values appended to the message. The event is built on a copy of the hub's
+// scope, so concurrent captures never see each other's extras.
+//
+// Records logged with a canceled or expired context are skipped if so
+// configured, or else sent without waiting for the transport. Records routed
+// to another project are sent with its client, and the same scope.
+func (h *sentryHandler) capture(ctx context.Context, record slog.Record) {
+	done := contextDone(ctx)
+	if done && h.skipCanceled {
+		return
+	}
+	hub := hubFromContext(ctx)
+	client := hub.Client()
+	if client == nil && h.router == nil {
+		return
+	}
+
+	scope := hub.Scope().Clone()
+	scope.SetTags(sentryTagsFromContext(ctx))
+	var errs []error
+	var msgValues []any
+	var msgFound []bool
+	var route any
+	if len(h.messageAttrs) > 0 {
+		msgValues = make([]any, len(h.messageAttrs))
+		msgFound = make([]bool, len(h.messageAttrs))
+	}
+
+	h.eachAttr(record, func(key string, v any) {
+		if err, ok := v.(error); ok {
+			errs = append(errs, err)
+			scope.SetExtra(key, err.Error()) // Most errors have no exported fields to encode
+		} else {
+			scope.SetExtra(key, v)
+		}
+		if h.router != nil && key == h.router.key {
+			route = v
+		}
+		for i, mk := range h.messageAttrs {
+			if mk == key {
+				msgValues[i], msgFound[i] = v, true
+			}
+		}
+	})
+
+	if routed := h.router.client(route); routed != nil {
+		client = routed
+	}
+	if client == nil {
+		return
+	}
+
+	// Skip errors already reported within this context
+	if !seenErrorsFromContext(ctx).report(errs) {
+		return
+	}
+
+	scope.SetLevel(slogToSentryLevel(record.Level)) // Map slog level to Sentry level
+	message := h.message(record.Message, msgValues, msgFound)
+	if done {
+		h.captureDetached(client, message, scope)
+		return
+	}
+	client.CaptureMessage(message, nil, scope)
+}
+
+// eachAttr calls fn with the resolved value of each attribute added with
+// WithAttrs and each attribute of the record, in that order. Keys are
+// qualified by the names of the enclosing groups, joined by dots, including
+// groups passed as attributes.
+func (h *sentryHandler) eachAttr(record slog.Record, fn func(key string, v any)) {
+	for _, a := range h.attrs {
+		eachFlatAttr("", a, fn)
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		eachFlatAttr(h.prefix, a, fn)
+		return true
+	})
+}
+
+// sentryFields returns the static Sentry fields as attributes, sorted by key.
+func sentryFields(fields map[string]any) []slog.Attr {
+	if len(fields) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slog.Any(k, fields[k])
+	}
+	return attrs
+}
+
+// eachFlatAttr calls fn with the key and resolved value of a, prefixed, or
+// of each attribute in it if it is a group, with the group name added to
+// the prefix.
+func eachFlatAttr(prefix string, a slog.Attr, fn func(key string, v any)) {
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		fn(prefix+a.Key, v.Any())
+		return
+	}
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, ga := range v.Group() {
+		eachFlatAttr(prefix, ga, fn)
+	}
+}
+
+// message appends the configured attributes found on the record to its message.
+func (h *sentryHandler) message(msg string, values []any, found []bool) string {
+	if len(h.messageAttrs) == 0 {
+		return msg
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for i, key := range h.messageAttrs {
+		if found[i] {
+			fmt.Fprintf(&b, " %s=%v", key, values[i])
+		}
+	}
+	return b.String()
+}
+
 // Helper function to map slog levels to Sentry levels
 func slogToSentryLevel(level slog.Level) sentry.Level {
 	switch level {
@@ -131,6 +1150,8 @@ func slogToSentryLevel(level slog.Level) sentry.Level {
 		return sentry.LevelWarning
 	case slog.LevelError:
 		return sentry.LevelError
+	case LevelFatal:
+		return sentry.LevelFatal
 	default:
 		return sentry.LevelInfo
 	}
@@ -143,32 +1164,100 @@ func (h *sentryHandler) Enabled(ctx context.Context, level slog.Level) bool {
 
 // WithAttrs returns a new handler with the given attributes.
 func (h *sentryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &sentryHandler{
-		next:        h.next,
-		minLogLevel: h.minLogLevel,
+	h2 := *h
+	h2.attrs = h.attrs[:len(h.attrs):len(h.attrs)]
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
 	}
+	return &h2
 }
 
 // WithGroup returns a new handler with the given group name.
 func (h *sentryHandler) WithGroup(name string) slog.Handler {
-	return &sentryHandler{
-		next:        h.next,
-		minLogLevel: h.minLogLevel,
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// multiHandler is a slog.Handler that fans records out to several handlers.
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+// Handle passes the record to every handler enabled for its level, and
+// returns the errors they report joined together.
+func (h *multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, record.Level) && !isInternal(ctx) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Enabled reports whether any handler is enabled for the given level.
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAttrs returns a new multi handler with the given attributes.
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
 	}
+	return &multiHandler{handlers: handlers}
 }
 
-// combinedHandler is a custom slog.Handler that combines JSON and Sentry handlers.
+// WithGroup returns a new multi handler with the given group name.
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// Close closes every handler that implements io.Closer and returns their
+// errors joined together.
+func (h *multiHandler) Close() error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if c, ok := handler.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// combinedHandler is a custom slog.Handler that combines output and Sentry handlers.
 type combinedHandler struct {
-	jsonHandler   slog.Handler
+	outputHandler slog.Handler
 	sentryHandler slog.Handler
 }
 
-// Handle processes the log record using both JSON and Sentry handlers.
+// Handle processes the log record using both output and Sentry handlers.
 func (h *combinedHandler) Handle(ctx context.Context, record slog.Record) error {
-	// First, handle the log with the JSON handler
-	err := h.jsonHandler.Handle(ctx, record)
-	if err != nil {
-		return err
+	// First, handle the log with the output handler, if its level allows or
+	// the record is the package's own
+	if h.outputHandler.Enabled(ctx, record.Level) || isInternal(ctx) {
+		if err := h.outputHandler.Handle(ctx, record); err != nil {
+			return err
+		}
 	}
 
 	// Then, handle the log with the Sentry handler
@@ -177,13 +1266,13 @@ func (h *combinedHandler) Handle(ctx context.Context, record slog.Record) error
 
 // Enabled determines if the handler is enabled for the given log level.
 func (h *combinedHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return h.jsonHandler.Enabled(ctx, level) || h.sentryHandler.Enabled(ctx, level)
+	return h.outputHandler.Enabled(ctx, level) || h.sentryHandler.Enabled(ctx, level)
 }
 
 // WithAttrs returns a new combined handler with the given attributes.
 func (h *combinedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return &combinedHandler{
-		jsonHandler:   h.jsonHandler.WithAttrs(attrs),
+		outputHandler: h.outputHandler.WithAttrs(attrs),
 		sentryHandler: h.sentryHandler.WithAttrs(attrs),
 	}
 }
@@ -191,7 +1280,7 @@ func (h *combinedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 // WithGroup returns a new combined handler with the given group name.
 func (h *combinedHandler) WithGroup(name string) slog.Handler {
 	return &combinedHandler{
-		jsonHandler:   h.jsonHandler.WithGroup(name),
+		outputHandler: h.outputHandler.WithGroup(name),
 		sentryHandler: h.sentryHandler.WithGroup(name),
 	}
 }
@@ -0,0 +1,1738 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// countingTransport is a sentry.Transport that counts the events it is
+// given and drops them.
+type countingTransport struct {
+	events atomic.Int64
+}
+
+func (t *countingTransport) Configure(sentry.ClientOptions) {}
+func (t *countingTransport) Flush(time.Duration) bool       { return true }
+func (t *countingTransport) SendEvent(*sentry.Event)        { t.events.Add(1) }
+
+// BenchmarkSentryCapture compares the capture of a record by the Sentry
+// handler, which sets extras in a single pass over its attributes, with
+// the map-based path it replaced, reproduced by captureWithMap.
+func BenchmarkSentryCapture(b *testing.B) {
+	// Without stack traces, which cost more than the rest of the capture,
+	// the difference between the paths is not lost in the noise
+	transport := &countingTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := sentry.SetHubOnContext(context.Background(), sentry.NewHub(client, sentry.NewScope()))
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "request failed", 0)
+	record.AddAttrs(
+		slog.String("service", "bench"),
+		slog.String("method", "GET"),
+		slog.Int("status", 500),
+		slog.Any("error", errors.New("upstream unavailable")),
+		slog.Group("user", slog.String("id", "42")),
+	)
+	h := &sentryHandler{minLogLevel: slog.LevelError}
+
+	benchmarks := []struct {
+		name    string
+		capture func()
+	}{
+		{name: "single pass", capture: func() { h.capture(ctx, record) }},
+		{name: "map", capture: func() { captureWithMap(ctx, record) }},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			transport.events.Store(0)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bm.capture()
+			}
+			b.StopTimer()
+
+			if got := transport.events.Load(); got != int64(b.N) {
+				b.Fatalf("transport got %d events, want %d", got, b.N)
+			}
+		})
+	}
+}
+
+// captureWithMap captures the record as the Sentry handler did before
+// extras were set in a single pass: the attributes are first collected in
+// a map, which is scanned for errors and then copied into the scope.
+func captureWithMap(ctx context.Context, record slog.Record) {
+	attrs := map[string]interface{}{}
+	record.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Any()
+		return true
+	})
+	var errs []error
+	for _, v := range attrs {
+		if err, ok := v.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	if !seenErrorsFromContext(ctx).report(errs) {
+		return
+	}
+
+	hub := hubFromContext(ctx)
+	scope := hub.Scope().Clone()
+	for k, v := range attrs {
+		scope.SetExtra(k, v)
+	}
+	scope.SetLevel(slogToSentryLevel(record.Level))
+	hub.Client().CaptureMessage(record.Message, nil, scope)
+}
+
+func TestRootKeyWithSizeAndChain(t *testing.T) {
+	var buf bytes.Buffer
+	key := []byte("secret")
+	l, err := New(Config{
+		Format:         "json",
+		Output:         &buf,
+		JSONRootKey:    "log",
+		IncludeSize:    true,
+		IntegrityChain: true,
+		IntegrityKey:   key,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		l.Info("hello", "i", i)
+	}
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var root map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &root); err != nil {
+			t.Fatalf("invalid JSON %s: %v", line, err)
+		}
+		if len(root) != 1 || root["log"] == nil {
+			t.Fatalf("fields outside the root object: %s", line)
+		}
+		var record struct {
+			Size  int    `json:"size"`
+			Chain string `json:"chain"`
+		}
+		if err := json.Unmarshal(root["log"], &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Chain == "" {
+			t.Errorf("no chain in the root object: %s", line)
+		}
+		// The size is that of the record as encoded, newline included,
+		// before the size and chain fields were added
+		fields := len(`,"size":`+strconv.Itoa(record.Size)) + len(`,"chain":""`) + len(record.Chain)
+		if want := len(root["log"]) - fields + 1; record.Size != want {
+			t.Errorf("size = %d, want %d: %s", record.Size, want, line)
+		}
+	}
+
+	if n, err := VerifyChain(strings.NewReader(buf.String()), key); n != 3 || err != nil {
+		t.Errorf("VerifyChain = %d, %v, want 3, nil", n, err)
+	}
+}
+
+func TestSequenceOutsideGroups(t *testing.T) {
+	var out, sinkOut bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &out, IncludeSequence: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	if _, err := AddSink(l, slog.NewJSONHandler(&sinkOut, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("first")
+	l.With("a", 1).WithGroup("g").With("b", 2).Info("second", "k", 3)
+	l.WithGroup("empty").Info("third")
+
+	want := []string{
+		`{"seq":1}`,
+		`{"a":1,"g":{"b":2,"k":3},"seq":2}`,
+		`{"seq":3}`,
+	}
+	for name, buf := range map[string]*bytes.Buffer{"output": &out, "sink": &sinkOut} {
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("%s: got %d lines, want %d:\n%s", name, len(lines), len(want), buf.String())
+		}
+		for i, line := range lines {
+			if got := userFields(t, line); got != want[i] {
+				t.Errorf("%s: record %d has %s, want %s", name, i+1, got, want[i])
+			}
+		}
+	}
+}
+
+// userFields returns the JSON record line without its time, level, message
+// and source, re-encoded with sorted keys.
+func userFields(t *testing.T, line string) string {
+	t.Helper()
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		t.Fatalf("invalid JSON %s: %v", line, err)
+	}
+	for _, key := range []string{slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey} {
+		delete(record, key)
+	}
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+func TestRecordID(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf, IncludeRecordID: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	l.WithGroup("g").Info("given", RecordID("abc"), "k", 1)
+	l.With(RecordID("with")).WithGroup("g").Info("with", "k", 1)
+	l.With(RecordID("with")).Info("overridden", RecordID("call"))
+	l.WithGroup("g").Info("generated", "k", 1)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{
+		`{"g":{"k":1},"record_id":"abc"}`,
+		`{"g":{"k":1},"record_id":"with"}`,
+		`{"record_id":"call"}`,
+	} {
+		if got := userFields(t, lines[i]); got != want {
+			t.Errorf("record %d has %s, want %s", i+1, got, want)
+		}
+		if n := strings.Count(lines[i], `"record_id"`); n != 1 {
+			t.Errorf("record %d has %d record_id keys: %s", i+1, n, lines[i])
+		}
+	}
+
+	var record struct {
+		G        map[string]any `json:"g"`
+		RecordID string         `json:"record_id"`
+	}
+	if err := json.Unmarshal([]byte(lines[3]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if len(record.RecordID) != 32 || record.G["record_id"] != nil {
+		t.Errorf("generated record ID not at the top level: %s", lines[3])
+	}
+}
+
+func TestRecordFieldsOutsideGroups(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{
+		Format:           "json",
+		Output:           &buf,
+		DefaultRetention: 48 * time.Hour,
+		MaxAttrs:         2,
+		EscalationRules:  []EscalationRule{{Attr: "g.retries", Op: ">", Value: 3, Level: slog.LevelError}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	l.WithGroup("g").Warn("retrying", "retries", 5, "a", 1, "b", 2)
+
+	want := `{"dropped_attrs":1,"escalated_from":"WARN","g":{"a":1,"retries":5},"retention":"2d"}`
+	if got := userFields(t, strings.TrimSpace(buf.String())); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if !strings.Contains(buf.String(), `"level":"ERROR"`) {
+		t.Errorf("record not escalated: %s", buf.String())
+	}
+}
+
+func TestGCPBuiltinFields(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "gcp", Output: &buf, SeverityScheme: "gcp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	l.With("level", "high").Warn("disk full", "msg", "user message", "source", "api", "size", 3)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		gcpMessageKey:  "disk full",
+		gcpSeverityKey: "WARNING",
+		"msg":          "user message",
+		"level":        "high",
+		"source":       "api",
+		"size":         float64(3),
+	}
+	for key, v := range want {
+		if record[key] != v {
+			t.Errorf("%s = %v, want %v", key, record[key], v)
+		}
+	}
+	if record[gcpSourceLocationKey] == nil {
+		t.Errorf("no source location: %s", buf.String())
+	}
+	for _, key := range []string{gcpMessageKey, gcpSeverityKey} {
+		if n := strings.Count(buf.String(), `"`+key+`":`); n != 1 {
+			t.Errorf("%d %q keys: %s", n, key, buf.String())
+		}
+	}
+}
+
+func TestGCPSeverityScheme(t *testing.T) {
+	for _, scheme := range []string{"syslog", "otel"} {
+		if _, err := New(Config{Format: "gcp", Output: io.Discard, SeverityScheme: scheme}); err == nil {
+			t.Errorf("New accepted severity scheme %q with the gcp format", scheme)
+		}
+	}
+}
+
+// countingMetrics counts the records passed to it per level.
+type countingMetrics struct {
+	mu     sync.Mutex
+	levels map[slog.Level]int
+}
+
+func (m *countingMetrics) Count(_ context.Context, level slog.Level, _ map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.levels == nil {
+		m.levels = make(map[slog.Level]int)
+	}
+	m.levels[level]++
+}
+
+func (m *countingMetrics) total() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, c := range m.levels {
+		n += c
+	}
+	return n
+}
+
+func TestHeartbeat(t *testing.T) {
+	var buf syncBuffer
+	metrics := &countingMetrics{}
+	l, err := New(Config{
+		LogLevel:          "error",
+		Format:            "json",
+		Output:            &buf,
+		HeartbeatInterval: 5 * time.Millisecond,
+		Metrics:           metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error("failed")
+	time.Sleep(30 * time.Millisecond)
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `"log_type":"heartbeat"`) {
+		t.Errorf("no heartbeat at LogLevel error:\n%s", buf.String())
+	}
+	if n := metrics.total(); n != 1 {
+		t.Errorf("metrics counted %d records, want only the error", n)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, for records
+// written from background goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSummaryOnClose(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{LogLevel: "error", Format: "json", Output: &buf, SummaryOnClose: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("not emitted")
+	l.Error("first")
+	l.Error("second")
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var summary struct {
+		LogType string           `json:"log_type"`
+		Records int              `json:"records"`
+		Levels  map[string]int64 `json:"levels"`
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.LogType != "summary" {
+		t.Fatalf("no summary at LogLevel error:\n%s", buf.String())
+	}
+	if summary.Records != 2 || summary.Levels["error"] != 2 || summary.Levels["info"] != 0 {
+		t.Errorf("summary reports %d records, levels %v, want the 2 errors", summary.Records, summary.Levels)
+	}
+}
+
+func TestSummaryOnCloseMetricsOnly(t *testing.T) {
+	metrics := &countingMetrics{}
+	l, err := New(Config{MetricsOnly: true, Metrics: metrics, SummaryOnClose: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("first")
+	l.Info("second")
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+	if n := metrics.total(); n != 2 {
+		t.Errorf("metrics counted %d records, want 2", n)
+	}
+}
+
+func TestAutoFormatOutput(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for name, out := range map[string]io.Writer{"file": f, "buffer": &bytes.Buffer{}} {
+		if got := outputFormat(Config{AutoFormat: true, Output: out}); got != "json" {
+			t.Errorf("%s: AutoFormat picked %q, want json", name, got)
+		}
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	key := []byte("secret")
+	tamper := []struct {
+		name   string
+		edit   func(lines []string) []string
+		wantN  int
+		key    []byte
+		wantOK bool
+	}{
+		{name: "intact", edit: func(l []string) []string { return l }, wantN: 4, key: key, wantOK: true},
+		{name: "modified", edit: func(l []string) []string {
+			l[2] = strings.Replace(l[2], "record 2", "record X", 1)
+			return l
+		}, wantN: 2, key: key},
+		{name: "removed", edit: func(l []string) []string {
+			return append(l[:1:1], l[2:]...)
+		}, wantN: 1, key: key},
+		{name: "reordered", edit: func(l []string) []string {
+			l[1], l[2] = l[2], l[1]
+			return l
+		}, wantN: 1, key: key},
+		{name: "wrong key", edit: func(l []string) []string { return l }, wantN: 0, key: []byte("other")},
+	}
+	formats := []struct {
+		name   string
+		config Config
+	}{
+		{name: "json", config: Config{Format: "json"}},
+		{name: "json root key", config: Config{Format: "json", JSONRootKey: "log", IncludeSize: true}},
+		{name: "gcp", config: Config{Format: "gcp"}},
+		{name: "text", config: Config{Format: "text"}},
+	}
+	for _, f := range formats {
+		var buf bytes.Buffer
+		config := f.config
+		config.Output = &buf
+		config.IntegrityChain = true
+		config.IntegrityKey = key
+		l, err := New(config)
+		if err != nil {
+			t.Fatalf("%s: %v", f.name, err)
+		}
+		for i := 0; i < 4; i++ {
+			l.Info("record "+strconv.Itoa(i), "i", i)
+		}
+		if err := Close(l); err != nil {
+			t.Fatal(err)
+		}
+		written := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+		for _, tt := range tamper {
+			t.Run(f.name+"/"+tt.name, func(t *testing.T) {
+				lines := tt.edit(append([]string(nil), written...))
+				n, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n")+"\n"), tt.key)
+				if n != tt.wantN || (err == nil) != tt.wantOK {
+					t.Errorf("VerifyChain = %d, %v, want %d records verified, ok %t", n, err, tt.wantN, tt.wantOK)
+				}
+			})
+		}
+	}
+}
+
+func TestIntegrityChainUnsupported(t *testing.T) {
+	for _, config := range []Config{
+		{Format: "csv"},
+		{Format: "msgpack"},
+		{Format: "text", TextIndent: 2},
+	} {
+		config.Output = io.Discard
+		config.IntegrityChain = true
+		if _, err := New(config); err == nil {
+			t.Errorf("New accepted an integrity chain with format %q, indent %d", config.Format, config.TextIndent)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{
+		Format:         "json",
+		Output:         &buf,
+		RedactKeys:     []string{"Password"},
+		RedactPaths:    []string{"config.db.dsn", "headers.*", "users.token", "req.body.card"},
+		RedactPatterns: []string{`sk_live_[0-9a-z]{4}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	type db struct {
+		DSN  string `json:"dsn"`
+		Pool int    `json:"pool"`
+	}
+	l.Info("key sk_live_abcd",
+		slog.Any("config", map[string]any{"db": db{DSN: "postgres://u:p@h", Pool: 4}}),
+		slog.Any("headers", map[string]string{"Cookie": "c"}),
+		slog.Any("users", []map[string]any{{"name": "a", "token": "t1"}, {"name": "b", "token": "t2"}}),
+		slog.Group("auth", slog.String("password", "hunter2"), slog.String("user", "a")),
+		slog.String("note", "uses sk_live_wxyz"),
+	)
+	l.WithGroup("req").With("body", `{"card":"4242","amount":3}`).Info("paid")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{
+		`{"auth":{"password":"[REDACTED]","user":"a"},` +
+			`"config":{"db":{"dsn":"[REDACTED]","pool":4}},` +
+			`"headers":{"Cookie":"[REDACTED]"},` +
+			`"note":"uses [REDACTED]",` +
+			`"users":[{"name":"a","token":"[REDACTED]"},{"name":"b","token":"[REDACTED]"}]}`,
+		`{"req":{"body":"{\"amount\":3,\"card\":\"[REDACTED]\"}"}}`,
+	} {
+		if got := userFields(t, lines[i]); got != want {
+			t.Errorf("record %d has\n %s\nwant\n %s", i+1, got, want)
+		}
+	}
+	if !strings.Contains(lines[0], `"msg":"key [REDACTED]"`) {
+		t.Errorf("message not masked: %s", lines[0])
+	}
+}
+
+func TestRedactInvalid(t *testing.T) {
+	for name, config := range map[string]Config{
+		"empty segment": {RedactPaths: []string{"a..b"}},
+		"too deep":      {RedactPaths: []string{"a.b.c"}, RedactMaxDepth: 2},
+		"empty key":     {RedactKeys: []string{""}},
+		"pattern":       {RedactPatterns: []string{"("}},
+	} {
+		config.Output = io.Discard
+		if _, err := New(config); err == nil {
+			t.Errorf("%s: New accepted invalid redaction rules", name)
+		}
+	}
+}
+
+func TestRedactPolicyFile(t *testing.T) {
+	dir := t.TempDir()
+	policy := filepath.Join(dir, "policy.json")
+	err := os.WriteFile(policy, []byte(`{"keys":["secret"],"paths":["a.b.c.d"],"max_depth":4}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	l, err := New(Config{
+		Format:           "json",
+		Output:           &buf,
+		RedactKeys:       []string{"password"},
+		RedactMaxDepth:   2,
+		RedactPolicyFile: policy,
+	})
+	if err != nil {
+		t.Fatalf("New rejected a path within the policy's max depth: %v", err)
+	}
+	defer Close(l)
+
+	l.Info("merged", "password", "p", "secret", "s",
+		slog.Any("a", map[string]any{"b": map[string]any{"c": map[string]any{"d": 1, "e": 2}}}))
+	want := `{"a":{"b":{"c":{"d":"[REDACTED]","e":2}}},"password":"[REDACTED]","secret":"[REDACTED]"}`
+	if got := userFields(t, strings.TrimSpace(buf.String())); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	for name, content := range map[string]string{
+		"unknown field": `{"keys":["secret"],"max_depht":4}`,
+		"invalid JSON":  `{"keys":`,
+		"invalid path":  `{"paths":["a..b"]}`,
+		"pii":           `{"pii":["passport"]}`,
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(Config{Output: io.Discard, RedactPolicyFile: path}); err == nil {
+			t.Errorf("%s: New accepted the policy %s", name, content)
+		}
+	}
+	if _, err := New(Config{Output: io.Discard, RedactPolicyFile: filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("New accepted a missing policy file")
+	}
+}
+
+func TestRedactMaxDepth(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{
+		Format:         "json",
+		Output:         &buf,
+		RedactKeys:     []string{"password"},
+		RedactPatterns: []string{"sk_[a-z]+"},
+		RedactMaxDepth: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	// At each depth, the keys and strings at the third level are redacted
+	// and those at the fourth are not.
+	nested := map[string]any{"password": "p", "note": "sk_abc", "c": map[string]any{"password": "p", "note": "sk_abc"}}
+	l.Info("depth",
+		slog.Group("g", slog.Group("b", "password", "p", "note", "sk_abc",
+			slog.Group("c", "password", "p", "note", "sk_abc"))),
+		slog.Any("m", map[string]any{"b": nested}),
+		slog.String("j", `{"b":{"password":"p","note":"sk_abc","c":{"password":"p","note":"sk_abc"}}}`),
+		slog.Any("a", []any{map[string]any{"b": nested}}),
+	)
+
+	deep := `"b":{"c":{"note":"sk_abc","password":"p"},"note":"[REDACTED]","password":"[REDACTED]"}`
+	want := `{"a":[{` + deep + `}],"g":{` + deep + `},` +
+		`"j":"{\"b\":{\"c\":{\"note\":\"sk_abc\",\"password\":\"p\"},\"note\":\"[REDACTED]\",\"password\":\"[REDACTED]\"}}",` +
+		`"m":{` + deep + `}}`
+	if got := userFields(t, strings.TrimSpace(buf.String())); got != want {
+		t.Errorf("got\n %s\nwant\n %s", got, want)
+	}
+
+	if _, err := New(Config{Output: io.Discard, RedactPaths: []string{"a.b.c"}, RedactMaxDepth: 3}); err != nil {
+		t.Errorf("New rejected a path as deep as the max depth: %v", err)
+	}
+}
+
+func TestRedactPII(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf, RedactPII: []string{"all"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	l.Info("signup from bob@example.com",
+		"card", "4242 4242 4242 4242",
+		"order", "4242 4242 4242 4241",
+		"ssn", "123-45-6789",
+		"not_ssn", "000-45-6789",
+		"phone", "call +1 (555) 123-4567 now",
+		slog.Any("user", map[string]any{"email": "a.b@mail.example.org"}),
+		slog.Any("err", errors.New("no account for eve@example.com")),
+	)
+
+	line := strings.TrimSpace(buf.String())
+	want := `{"card":"[REDACTED]","err":"no account for [REDACTED]","not_ssn":"000-45-6789",` +
+		`"order":"4242 4242 4242 4241","phone":"call [REDACTED] now","ssn":"[REDACTED]",` +
+		`"user":{"email":"[REDACTED]"}}`
+	if got := userFields(t, line); got != want {
+		t.Errorf("got\n %s\nwant\n %s", got, want)
+	}
+	if !strings.Contains(line, `"msg":"signup from [REDACTED]"`) {
+		t.Errorf("message not masked: %s", line)
+	}
+
+	if _, err := New(Config{Output: io.Discard, RedactPII: []string{"passport"}}); err == nil {
+		t.Error("New accepted an unknown PII detector")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{
+		Format:       "csv",
+		Output:       &buf,
+		CSVColumns:   []string{slog.LevelKey, slog.MessageKey, "user", "req.path"},
+		CSVHeader:    true,
+		CSVPackAttrs: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	l.Info("hello, world", "user", `say "hi"`)
+	l.WithGroup("req").Warn("multi\nline", "path", "/a,b", "note", `quoted "x", comma`)
+	l.Info("columns only", "user", "u")
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v\n%s", err, buf.String())
+	}
+	want := [][]string{
+		{"level", "msg", "user", "req.path", "attrs"},
+		{"INFO", "hello, world", `say "hi"`, "", ""},
+		{"WARN", "multi\nline", "", "/a,b", `{"req.note":"quoted \"x\", comma"}`},
+		{"INFO", "columns only", "u", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows\n %q\nwant\n %q", rows, want)
+	}
+}
+
+func TestBufferedOutput(t *testing.T) {
+	var out syncBuffer
+	l, err := New(Config{Format: "json", Output: &out, BufferedOutput: true, BufferSize: 1 << 16, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("buffered")
+	if got := out.String(); got != "" {
+		t.Errorf("record written before a flush: %s", got)
+	}
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"msg":"buffered"`) {
+		t.Errorf("record not flushed on Close: %q", out.String())
+	}
+
+	var periodic syncBuffer
+	l, err = New(Config{Format: "json", Output: &periodic, BufferedOutput: true, FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	l.Info("flushed")
+	for deadline := time.Now().Add(5 * time.Second); periodic.String() == ""; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("record not flushed periodically")
+		}
+	}
+}
+
+// newDryRunLogger returns a logger created by New with config, with Sentry
+// enabled in dry-run mode, and a function returning the Sentry events
+// logged so far. The events are written to a temporary file standing in
+// for stderr.
+func newDryRunLogger(t *testing.T, config Config) (Logger, func() []map[string]any) {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	stderr := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = stderr }()
+
+	config.EnableSentry = true
+	config.SentryDryRun = true
+	if config.Output == nil {
+		config.Output = io.Discard
+	}
+	l, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close(l) })
+
+	return l, func() []map[string]any {
+		t.Helper()
+		b, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			if line == "" {
+				continue
+			}
+			var event map[string]any
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				t.Fatalf("invalid dry-run event %s: %v", line, err)
+			}
+			events = append(events, event)
+		}
+		return events
+	}
+}
+
+func TestSentryMessageAttrs(t *testing.T) {
+	l, events := newDryRunLogger(t, Config{SentryMessageAttrs: []string{"status", "req.method", "missing"}})
+
+	l.WithGroup("req").Error("request failed", "method", "GET", "path", "/")
+	l.Error("upstream failed", "status", 502)
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2: %v", len(got), got)
+	}
+	for i, want := range []string{"request failed req.method=GET", "upstream failed status=502"} {
+		if msg := got[i]["event_message"]; msg != want {
+			t.Errorf("event %d has message %q, want %q", i+1, msg, want)
+		}
+	}
+}
+
+func TestIncludeGitInfo(t *testing.T) {
+	t.Setenv("GIT_COMMIT", "0123abc")
+	t.Setenv("GIT_BRANCH", "main")
+	t.Setenv("SENTRY_RELEASE", "")
+
+	var buf bytes.Buffer
+	l, events := newDryRunLogger(t, Config{Format: "json", Output: &buf, IncludeGitInfo: true})
+	l.Error("failed")
+
+	if got, want := userFields(t, strings.TrimSpace(buf.String())), `{"git_branch":"main","git_commit":"0123abc"}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got := events(); len(got) != 1 || got[0]["release"] != "0123abc" {
+		t.Errorf("Sentry events %v, want one with release 0123abc", got)
+	}
+}
+
+// newPushServer returns a server recording the path and body of each
+// request it accepts, and a function returning the bodies received so far.
+func newPushServer(t *testing.T, path string) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("request to %s, want %s", r.URL.Path, path)
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+func TestLoki(t *testing.T) {
+	srv, pushes := newPushServer(t, lokiPushPath)
+	l, err := New(Config{
+		Format:         "json",
+		Output:         io.Discard,
+		LokiURL:        srv.URL + "/",
+		LokiLabels:     map[string]string{"app": "api", "team-name": "core"},
+		LokiLabelAttrs: []string{"tenant"},
+		LokiBatchSize:  2,
+		LokiBatchWait:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("one", "tenant", "a")
+	l.With("tenant", "b").Warn("two")
+	l.WithGroup("g").Info("three", "tenant", "c")
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := pushes()
+	if len(bodies) != 2 {
+		t.Fatalf("got %d pushes, want 2 batches: %v", len(bodies), bodies)
+	}
+	var streams []map[string]string
+	for _, body := range bodies {
+		var payload struct {
+			Streams []lokiStream `json:"streams"`
+		}
+		if err := json.Unmarshal([]byte(body), &payload); err != nil {
+			t.Fatalf("invalid push %s: %v", body, err)
+		}
+		for _, s := range payload.Streams {
+			if len(s.Values) != 1 {
+				t.Errorf("stream %v has %d values, want 1", s.Stream, len(s.Values))
+			}
+			streams = append(streams, s.Stream)
+		}
+	}
+	want := []map[string]string{
+		{"app": "api", "team_name": "core", "level": "info", "tenant": "a"},
+		{"app": "api", "team_name": "core", "level": "warn", "tenant": "b"},
+		{"app": "api", "team_name": "core", "level": "info"},
+	}
+	if !reflect.DeepEqual(streams, want) {
+		t.Errorf("got streams %v, want %v", streams, want)
+	}
+
+	if _, err := New(Config{Output: io.Discard, LokiURL: srv.URL, LokiLabelAttrs: make([]string, maxLokiLabelAttrs+1)}); err == nil {
+		t.Error("New accepted too many Loki label attributes")
+	}
+}
+
+type sampledKey struct{}
+
+func TestSampleByTrace(t *testing.T) {
+	traceSampled := func(ctx context.Context) (sampled, ok bool) {
+		sampled, ok = ctx.Value(sampledKey{}).(bool)
+		return sampled, ok
+	}
+	sampledCtx := context.WithValue(context.Background(), sampledKey{}, true)
+	unsampledCtx := context.WithValue(context.Background(), sampledKey{}, false)
+
+	for _, byTrace := range []bool{true, false} {
+		var buf bytes.Buffer
+		l, err := New(Config{
+			Format:        "json",
+			Output:        &buf,
+			SampleRate:    1e-9,
+			SampleByTrace: byTrace,
+			TraceSampled:  traceSampled,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			l.InfoContext(sampledCtx, "sampled")
+			l.InfoContext(unsampledCtx, "unsampled")
+			l.Info("no trace")
+			l.InfoContext(ForceTrace(unsampledCtx), "forced")
+			l.WarnContext(unsampledCtx, "warning")
+		}
+		Close(l)
+
+		want := map[string]int{"forced": 10, "warning": 10}
+		if byTrace {
+			want["sampled"] = 10
+		}
+		got := map[string]int{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record struct{ Msg string }
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatal(err)
+			}
+			got[record.Msg]++
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SampleByTrace %t: kept %v, want %v", byTrace, got, want)
+		}
+	}
+}
+
+func TestBaggageKeys(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf, BaggageKeys: []string{"tenant", "region", "user"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	ctx := WithAttrs(context.Background(), "user", "from-attrs", "request_id", "r1")
+	ctx = WithBaggage(ctx, map[string]string{"tenant": "t1", "user": "from-baggage", "secret": "s"})
+	ctx = WithBaggage(ctx, map[string]string{"region": "eu"})
+	l.InfoContext(ctx, "merged", "request_id", "from-record")
+
+	// Record attributes win over context attributes, which win over baggage,
+	// and baggage entries not listed are left out.
+	want := `{"region":"eu","request_id":"from-record","tenant":"t1","user":"from-attrs"}`
+	if got := userFields(t, strings.TrimSpace(buf.String())); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestEmitSchema(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{
+		Format:          "json",
+		Output:          &buf,
+		LogLevel:        "error",
+		EmitSchema:      true,
+		IncludeSequence: true,
+		IncludeRecordID: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error("failed")
+	Close(l)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want the schema and a record:\n%s", len(lines), buf.String())
+	}
+	var schema struct {
+		LogType string        `json:"log_type"`
+		Version int           `json:"schema_version"`
+		Fields  []schemaField `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.LogType != "schema" || schema.Version != schemaVersion {
+		t.Errorf("first record is not the schema descriptor: %s", lines[0])
+	}
+
+	// Every field the schema does not mark optional is in the record.
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range schema.Fields {
+		names = append(names, f.Name)
+		if _, ok := record[f.Name]; !f.Optional && !ok {
+			t.Errorf("record has no %s field: %s", f.Name, lines[1])
+		}
+	}
+	for _, name := range []string{sequenceKey, recordIDKey} {
+		if !slices.Contains(names, name) {
+			t.Errorf("schema has no %s field: %v", name, names)
+		}
+	}
+}
+
+func TestSentryBreadcrumbs(t *testing.T) {
+	l, events := newDryRunLogger(t, Config{LogLevel: "debug", SentryBreadcrumbs: true, SentryMaxBreadcrumbs: 2})
+
+	ctx := WithSentryHub(context.Background())
+	other := WithSentryHub(context.Background())
+	l.DebugContext(ctx, "one")
+	l.InfoContext(ctx, "two")
+	l.InfoContext(ctx, "three")
+	l.InfoContext(other, "other request")
+	l.Info("no hub")
+	l.ErrorContext(ctx, "failed")
+	l.ErrorContext(WithSentryHub(context.Background()), "failed alone")
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2: %v", len(got), got)
+	}
+	if n := got[0]["breadcrumbs"]; n != float64(2) {
+		t.Errorf("event has %v breadcrumbs, want the last 2 of its request", n)
+	}
+	if n, ok := got[1]["breadcrumbs"]; ok {
+		t.Errorf("event of a request without breadcrumbs has %v", n)
+	}
+}
+
+func TestIncludeProcessIdentity(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf, IncludeProcessIdentity: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	l.Info("identified")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if euid := os.Geteuid(); euid >= 0 && record["euid"] != float64(euid) {
+		t.Errorf("euid = %v, want %d", record["euid"], euid)
+	}
+	if u, err := user.Current(); err == nil && record["os_user"] != u.Username {
+		t.Errorf("os_user = %v, want %s", record["os_user"], u.Username)
+	}
+}
+
+func TestStackTraceDepth(t *testing.T) {
+	l, events := newDryRunLogger(t, Config{StackTraceDepth: 1})
+	// The frames of this package, tests included, are removed as logging
+	// machinery, so the record is logged through sync, whose frames stand
+	// for those of an application
+	sync.OnceFunc(func() { l.Error("failed") })()
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	if n := got[0]["stack_frames"]; n != float64(1) {
+		t.Errorf("event has %v stack frames, want 1", n)
+	}
+	if extra, _ := got[0]["extra"].(map[string]any); extra["stacktrace_truncated"] == nil {
+		t.Errorf("event does not report the truncated frames: %v", got[0])
+	}
+}
+
+func TestIncludeLocalTime(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+	defer func() { time.Local = local }()
+
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf, IncludeLocalTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	l.WithGroup("g").Info("timed", "time", "not a built-in")
+
+	var record struct {
+		Time      string            `json:"time"`
+		LocalTime string            `json:"local_time"`
+		G         map[string]string `json:"g"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	utc, err := time.Parse(time.RFC3339Nano, record.Time)
+	if err != nil || !strings.HasSuffix(record.Time, "Z") {
+		t.Fatalf("time %q is not in UTC: %v", record.Time, err)
+	}
+	if want := utc.In(time.Local).Format(localTimeLayout); record.LocalTime != want || !strings.HasSuffix(want, "+02:00") {
+		t.Errorf("local_time = %q, want %q", record.LocalTime, want)
+	}
+	if record.G["time"] != "not a built-in" {
+		t.Errorf("grouped time attribute replaced: %s", buf.String())
+	}
+}
+
+func TestMessageOnly(t *testing.T) {
+	var buf bytes.Buffer
+	l, events := newDryRunLogger(t, Config{
+		Format:          "json",
+		Output:          &buf,
+		MessageOnly:     true,
+		IncludeSequence: true,
+		SentryFields:    map[string]any{"team": "core"},
+	})
+
+	ctx := WithAttrs(context.Background(), "request_id", "r1")
+	l.With("a", 1).WithGroup("g").ErrorContext(ctx, "failed", "err", errors.New("boom"))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for k := range record {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	if want := []string{slog.LevelKey, slog.MessageKey, slog.TimeKey}; !slices.Equal(keys, want) {
+		t.Errorf("record has fields %v, want %v", keys, want)
+	}
+	if got := events(); len(got) != 1 || got[0]["extra"] != nil {
+		t.Errorf("Sentry events %v, want one without extras", got)
+	}
+}
+
+func TestSentryDryRun(t *testing.T) {
+	l, events := newDryRunLogger(t, Config{})
+	l.Info("not sent")
+	l.Warn("slow", "ms", 900)
+	l.Error("failed", "err", errors.New("boom"))
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2: %v", len(got), got)
+	}
+	for i, want := range []struct {
+		level, message string
+		extra          map[string]any
+	}{
+		{level: "warning", message: "slow", extra: map[string]any{"ms": float64(900)}},
+		{level: "error", message: "failed", extra: map[string]any{"err": "boom"}},
+	} {
+		e := got[i]
+		if e[logTypeKey] != "sentry_dry_run" || e["event_level"] != want.level || e["event_message"] != want.message {
+			t.Errorf("event %d: %v, want a %s event %q", i+1, e, want.level, want.message)
+		}
+		if !reflect.DeepEqual(e["extra"], want.extra) {
+			t.Errorf("event %d has extras %v, want %v", i+1, e["extra"], want.extra)
+		}
+	}
+}
+
+func TestSentryCanceledContexts(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	async, asyncEvents := newDryRunLogger(t, Config{})
+	async.ErrorContext(canceled, "failed after cancel")
+	for deadline := time.Now().Add(5 * time.Second); len(asyncEvents()) == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("event for a canceled context not sent")
+		}
+	}
+	if got := asyncEvents(); got[0]["event_message"] != "failed after cancel" || got[0]["event_level"] != "error" {
+		t.Errorf("unexpected event %v", got[0])
+	}
+
+	var buf bytes.Buffer
+	skip, skipEvents := newDryRunLogger(t, Config{Format: "json", Output: &buf, SentryCanceledContexts: "skip"})
+	skip.ErrorContext(canceled, "failed after cancel")
+	skip.Error("failed")
+	if got := skipEvents(); len(got) != 1 || got[0]["event_message"] != "failed" {
+		t.Errorf("Sentry events %v, want only the one for a live context", got)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("output got %d records, want both", n)
+	}
+
+	if _, err := New(Config{Output: io.Discard, SentryCanceledContexts: "drop"}); err == nil {
+		t.Error("New accepted an unknown canceled context mode")
+	}
+}
+
+type tenantKey struct{}
+
+func TestEnrichers(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{
+		Format: "json",
+		Output: &buf,
+		Enrichers: []Enricher{
+			StaticEnricher("service", "api"),
+			ContextValueEnricher("tenant", tenantKey{}),
+			GoroutineEnricher(func() []slog.Attr { return []slog.Attr{slog.Int("worker", 3)} }),
+			EnricherFunc(func(_ context.Context, record *slog.Record) {
+				record.AddAttrs(slog.Int("attrs", record.NumAttrs()))
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "t1")
+	l.InfoContext(ctx, "enriched", "k", 1)
+	l.WithGroup("g").Info("grouped")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{
+		`{"attrs":4,"k":1,"service":"api","tenant":"t1","worker":3}`,
+		`{"g":{"attrs":2,"service":"api","worker":3}}`,
+	} {
+		if got := userFields(t, lines[i]); got != want {
+			t.Errorf("record %d has %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestFatal(t *testing.T) {
+	var out syncBuffer
+	code := -1
+	l, events := newDryRunLogger(t, Config{
+		Format:         "json",
+		Output:         &out,
+		BufferedOutput: true,
+		FlushInterval:  time.Hour,
+		ExitFunc:       func(c int) { code = c },
+	})
+
+	Fatal(l.With("component", "db"), "cannot start", "err", errors.New("no such host"))
+
+	if code != 1 {
+		t.Fatalf("exit code %d, want ExitFunc called with 1", code)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(out.String()), &record); err != nil {
+		t.Fatalf("record not flushed before exiting: %q", out.String())
+	}
+	if record[slog.LevelKey] != "FATAL" || record["component"] != "db" || record["err"] != "no such host" {
+		t.Errorf("unexpected record %v", record)
+	}
+	if got := events(); len(got) != 1 || got[0]["event_level"] != "fatal" {
+		t.Errorf("Sentry events %v, want one fatal event", got)
+	}
+}
+
+func TestRuntimeStats(t *testing.T) {
+	var buf syncBuffer
+	l, err := New(Config{LogLevel: "debug", Format: "json", Output: &buf, RuntimeStatsInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(buf.String(), "runtime stats"); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("no runtime stats record")
+		}
+	}
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+
+	line, _, _ := strings.Cut(buf.String(), "\n")
+	var record map[string]any
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[logTypeKey] != "runtime" || record[slog.LevelKey] != "DEBUG" {
+		t.Errorf("unexpected runtime stats record %s", line)
+	}
+	for _, key := range []string{"goroutines", "heap_alloc", "sys", "num_gc"} {
+		if _, ok := record[key].(float64); !ok {
+			t.Errorf("runtime stats record has no %s: %s", key, line)
+		}
+	}
+}
+
+func TestGCPProjectID(t *testing.T) {
+	const traceID, spanID = "0123456789abcdef0123456789abcdef", "0123456789abcdef"
+	ctx := ExtractContext(context.Background(), map[string]string{
+		sentry.SentryTraceHeader: traceID + "-" + spanID + "-1",
+	})
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "from-env")
+
+	for _, tt := range []struct {
+		projectID, want string
+	}{
+		{projectID: "my-project", want: "projects/my-project/traces/" + traceID},
+		{projectID: "", want: "projects/from-env/traces/" + traceID},
+	} {
+		var buf bytes.Buffer
+		l, err := New(Config{Format: "gcp", Output: &buf, GCPProjectID: tt.projectID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.InfoContext(ctx, "traced")
+		l.Info("untraced")
+		Close(l)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		var traced, untraced map[string]any
+		if err := json.Unmarshal([]byte(lines[0]), &traced); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(lines[1]), &untraced); err != nil {
+			t.Fatal(err)
+		}
+		if traced[gcpTraceKey] != tt.want || traced[gcpSpanIDKey] != spanID || traced[gcpTraceSampledKey] != true {
+			t.Errorf("project %q: unexpected trace fields %s", tt.projectID, lines[0])
+		}
+		if _, ok := untraced[gcpTraceKey]; ok {
+			t.Errorf("project %q: trace on a record without one: %s", tt.projectID, lines[1])
+		}
+	}
+}
+
+// panicEnricher panics on records with a "panic" attribute.
+var panicEnricher = EnricherFunc(func(_ context.Context, record *slog.Record) {
+	record.Attrs(func(a slog.Attr) bool {
+		if a.Key == "panic" {
+			panic("cannot enrich")
+		}
+		return true
+	})
+})
+
+func TestPanicRecovery(t *testing.T) {
+	var reports bytes.Buffer
+	saved := panicLogger
+	panicLogger = slog.New(slog.NewJSONHandler(&reports, nil))
+	defer func() { panicLogger = saved }()
+
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf, RedactPatterns: []string{"secret"}, Enrichers: []Enricher{panicEnricher}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	l.Info("token secret", "panic", true)
+	l.Info("after")
+
+	var report map[string]any
+	if err := json.Unmarshal(reports.Bytes(), &report); err != nil {
+		t.Fatalf("panic not reported: %q", reports.String())
+	}
+	if report[logTypeKey] != "handler_panic" || report["panic"] != "cannot enrich" || report["record_msg"] != "token [REDACTED]" {
+		t.Errorf("unexpected report %v", report)
+	}
+	if !strings.Contains(buf.String(), `"msg":"after"`) {
+		t.Errorf("logger unusable after a panic:\n%s", buf.String())
+	}
+
+	l, err = New(Config{Format: "json", Output: io.Discard, PropagatePanics: true, Enrichers: []Enricher{panicEnricher}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	defer func() {
+		if v := recover(); v != "cannot enrich" {
+			t.Errorf("recovered %v, want the panic of the enricher", v)
+		}
+	}()
+	l.Info("propagated", "panic", true)
+	t.Error("panic not propagated")
+}
+
+func TestRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &buf, RateLimit: 0.001, RateBurst: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		l.Info("limited")
+	}
+	l.Warn("never limited")
+	l.InfoContext(ForceTrace(context.Background()), "forced")
+	Close(l)
+
+	if got := strings.Count(buf.String(), `"msg":"limited"`); got != 3 {
+		t.Errorf("kept %d info records, want the burst of 3", got)
+	}
+	for _, msg := range []string{"never limited", "forced"} {
+		if !strings.Contains(buf.String(), `"msg":"`+msg+`"`) {
+			t.Errorf("%q record limited", msg)
+		}
+	}
+}
+
+func TestAzure(t *testing.T) {
+	srv, pushes := newPushServer(t, azureTrackPath)
+	l, err := New(Config{
+		Format:                "json",
+		Output:                io.Discard,
+		AzureConnectionString: "InstrumentationKey=key1;IngestionEndpoint=" + srv.URL + "/",
+		AzureBatchSize:        10,
+		AzureBatchWait:        time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.With("service", "api").WithGroup("req").Warn("slow", "ms", 900)
+	l.Error("cannot open", "err", &os.PathError{Op: "open", Path: "/x", Err: os.ErrNotExist})
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := pushes()
+	if len(bodies) != 1 {
+		t.Fatalf("got %d requests, want one batch: %v", len(bodies), bodies)
+	}
+	var envelopes []struct {
+		Name string `json:"name"`
+		IKey string `json:"iKey"`
+		Data struct {
+			BaseType string `json:"baseType"`
+			BaseData struct {
+				Message       string            `json:"message"`
+				SeverityLevel int               `json:"severityLevel"`
+				Properties    map[string]string `json:"properties"`
+				Exceptions    []azureException  `json:"exceptions"`
+			} `json:"baseData"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &envelopes); err != nil {
+		t.Fatalf("invalid batch %s: %v", bodies[0], err)
+	}
+	if len(envelopes) != 2 {
+		t.Fatalf("got %d envelopes, want 2: %s", len(envelopes), bodies[0])
+	}
+
+	trace, exception := envelopes[0], envelopes[1]
+	if trace.IKey != "key1" || trace.Data.BaseType != "MessageData" || trace.Data.BaseData.Message != "slow" ||
+		trace.Data.BaseData.SeverityLevel != azureWarning {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+	props := trace.Data.BaseData.Properties
+	if props["service"] != "api" || props["req.ms"] != "900" || !strings.Contains(props[slog.SourceKey], "logger_test.go:") {
+		t.Errorf("unexpected trace properties %v", props)
+	}
+	want := []azureException{{TypeName: "*fs.PathError", Message: "cannot open: open /x: file does not exist"}}
+	if exception.Data.BaseType != "ExceptionData" || !reflect.DeepEqual(exception.Data.BaseData.Exceptions, want) ||
+		exception.Data.BaseData.SeverityLevel != azureError {
+		t.Errorf("unexpected exception %+v", exception)
+	}
+
+	if _, err := New(Config{Output: io.Discard, AzureConnectionString: "IngestionEndpoint=" + srv.URL}); err == nil {
+		t.Error("New accepted a connection string without an instrumentation key")
+	}
+}
+
+func TestSentryRoutes(t *testing.T) {
+	var mu sync.Mutex
+	projects := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Events are posted to /api/<project>/envelope/
+		project := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1]
+		mu.Lock()
+		projects[project]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	dsn := func(project string) string {
+		return strings.Replace(srv.URL, "://", "://public@", 1) + "/" + project
+	}
+
+	l, err := New(Config{
+		Output:          io.Discard,
+		EnableSentry:    true,
+		SentryDSN:       dsn("1"),
+		SentryRouteAttr: "tenant",
+		SentryRoutes:    map[string]string{"acme": dsn("2"), "globex": dsn("3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Error("routed", "tenant", "acme")
+	l.With("tenant", "globex").Error("routed with With")
+	l.Error("unrouted", "tenant", "initech")
+	l.Error("no tenant")
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := map[string]int{"1": 2, "2": 1, "3": 1}; !reflect.DeepEqual(projects, want) {
+		t.Errorf("events per project %v, want %v", projects, want)
+	}
+
+	for name, config := range map[string]Config{
+		"no attribute": {SentryRoutes: map[string]string{"acme": dsn("2")}},
+		"invalid DSN":  {SentryRouteAttr: "tenant", SentryRoutes: map[string]string{"acme": "not a DSN"}},
+	} {
+		config.Output = io.Discard
+		config.EnableSentry = true
+		config.SentryDSN = dsn("1")
+		if _, err := New(config); err == nil {
+			t.Errorf("%s: New accepted the Sentry routes", name)
+		}
+	}
+}
+
+func TestSentryFields(t *testing.T) {
+	var buf bytes.Buffer
+	l, events := newDryRunLogger(t, Config{
+		Format:       "json",
+		Output:       &buf,
+		SentryFields: map[string]any{"team": "core", "region": "eu", "password": "p"},
+		RedactKeys:   []string{"password"},
+	})
+	l.Error("failed", "region", "us")
+
+	if got := userFields(t, strings.TrimSpace(buf.String())); got != `{"region":"us"}` {
+		t.Errorf("Sentry fields written to the output: %s", got)
+	}
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	want := map[string]any{"team": "core", "region": "us", "password": "[REDACTED]"}
+	if !reflect.DeepEqual(got[0]["extra"], want) {
+		t.Errorf("event extras %v, want %v", got[0]["extra"], want)
+	}
+}
+
+// labelMetrics is a Metrics recording the labels of each count.
+type labelMetrics struct {
+	mu     sync.Mutex
+	counts []map[string]string
+}
+
+func (m *labelMetrics) Count(_ context.Context, _ slog.Level, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts = append(m.counts, labels)
+}
+
+func TestMetricsLabels(t *testing.T) {
+	metrics := &labelMetrics{}
+	l, err := New(Config{
+		Output:        io.Discard,
+		SampleRate:    1e-9,
+		Metrics:       metrics,
+		MetricsLabels: []string{"service", "http.method"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	l.With("service", "api").WithGroup("http").Info("request", "method", "GET", "path", "/")
+	l.Info("plain", slog.Group("http", slog.String("method", "POST")))
+	l.Info("unlabeled", "method", "PUT")
+
+	want := []map[string]string{
+		{"service": "api", "http.method": "GET"},
+		{"service": "", "http.method": "POST"},
+		{"service": "", "http.method": ""},
+	}
+	if !reflect.DeepEqual(metrics.counts, want) {
+		t.Errorf("counted labels %v, want %v, including sampled out records", metrics.counts, want)
+	}
+}
+
+func TestBatchSettings(t *testing.T) {
+	loki, lokiPushes := newPushServer(t, lokiPushPath)
+	azure, azurePushes := newPushServer(t, azureTrackPath)
+	l, err := New(Config{
+		Output:                io.Discard,
+		LokiURL:               loki.URL,
+		AzureConnectionString: "InstrumentationKey=k;IngestionEndpoint=" + azure.URL,
+		BatchSize:             2,
+		BatchWait:             time.Hour,
+		AzureBatchSize:        3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		l.Info("batched")
+	}
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lokiPushes()); n != 2 {
+		t.Errorf("Loki got %d pushes, want 2 with the shared batch size", n)
+	}
+	if n := len(azurePushes()); n != 1 {
+		t.Errorf("Application Insights got %d requests, want 1 with its own batch size", n)
+	}
+
+	// A short shared wait sends records before the batch is full.
+	l, err = New(Config{Output: io.Discard, LokiURL: loki.URL, BatchWait: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	l.Info("waited")
+	for deadline := time.Now().Add(5 * time.Second); len(lokiPushes()) < 3; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("record not pushed after BatchWait")
+		}
+	}
+}
+
+func TestStrictNDJSON(t *testing.T) {
+	var out syncBuffer
+	l, err := New(Config{
+		Format:          "json",
+		Output:          &out,
+		StrictNDJSON:    true,
+		BufferedOutput:  true,
+		FlushInterval:   time.Hour,
+		FlushEachRecord: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+	l.Info("multi\nline", "v", "a\r\nb")
+
+	// Flushed without waiting for the interval or Close
+	line := out.String()
+	if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") || !json.Valid([]byte(line)) {
+		t.Errorf("record not written as one flushed JSON line: %q", line)
+	}
+
+	if _, err := New(Config{Format: "text", Output: io.Discard, StrictNDJSON: true}); err == nil {
+		t.Error("New accepted strict NDJSON with the text format")
+	}
+}
+
+func TestSentryQuietHours(t *testing.T) {
+	now := time.Now().UTC()
+	window := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	l, events := newDryRunLogger(t, Config{SentryQuietHours: window, SentryQuietTimezone: "UTC"})
+
+	ctx := WithSentryHub(context.Background())
+	l.WarnContext(ctx, "slow")
+	l.ErrorContext(ctx, "failed")
+
+	got := events()
+	if len(got) != 1 || got[0]["event_message"] != "failed" {
+		t.Fatalf("Sentry events %v, want only the error during quiet hours", got)
+	}
+	if got[0]["breadcrumbs"] != float64(1) {
+		t.Errorf("event has %v breadcrumbs, want the quieted warning", got[0]["breadcrumbs"])
+	}
+
+	for _, config := range []Config{
+		{SentryQuietHours: "22:00"},
+		{SentryQuietHours: "25:00-06:00"},
+		{SentryQuietHours: "06:00-06:00"},
+		{SentryQuietHours: "22:00-06:00", SentryQuietTimezone: "Nowhere/City"},
+	} {
+		config.Output = io.Discard
+		if _, err := New(config); err == nil {
+			t.Errorf("New accepted quiet hours %q in zone %q", config.SentryQuietHours, config.SentryQuietTimezone)
+		}
+	}
+}
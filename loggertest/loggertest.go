@@ -0,0 +1,55 @@
+// Package loggertest provides loggers for tests, kept apart from package
+// logger so that programs using it do not link the testing package.
+package loggertest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stratastor/logger"
+)
+
+// NewTB returns a debug-level logger writing records in the text format
+// through t.Log, so that they appear in the output of the test that logged
+// them, and only with -v or when the test fails. Records logged after the
+// test has completed, such as from goroutines it left behind, are dropped
+// rather than making the test panic. The logger is closed when the test and
+// its subtests complete.
+//
+// Each test of a parallel run should create its own logger, so that its
+// records are attributed to it.
+func NewTB(t testing.TB) logger.Logger {
+	t.Helper()
+
+	w := &tbWriter{t: t}
+	l, err := logger.New(logger.Config{LogLevel: "debug", Format: "text", Output: w})
+	if err != nil {
+		t.Fatalf("creating test logger: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = logger.Close(l)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.done = true
+	})
+	return l
+}
+
+// tbWriter writes each record to t.Log, until the test is done.
+type tbWriter struct {
+	t    testing.TB
+	mu   sync.Mutex
+	done bool
+}
+
+// Write logs p, a single record, without its trailing newline.
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.t.Helper()
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
@@ -0,0 +1,230 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLokiLabelAttrs bounds the attributes promoted to labels, since every
+	// distinct label combination creates a separate Loki stream.
+	maxLokiLabelAttrs = 8
+	lokiPushPath      = "/loki/api/v1/push"
+)
+
+// lokiHandler is a slog.Handler that pushes records to Grafana Loki. Records
+// are encoded as JSON lines and queued; a background goroutine batches them
+// into streams keyed by label set and pushes them to Loki's HTTP API.
+type lokiHandler struct {
+	json      slog.Handler
+	enc       *lokiEncoder
+	labelKeys map[string]bool
+	labels    map[string]string
+	grouped   bool
+	client    *lokiClient
+}
+
+// lokiEncoder captures the output of the JSON handler one record at a time.
+type lokiEncoder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (e *lokiEncoder) Write(p []byte) (int, error) {
+	return e.buf.Write(p)
+}
+
+// newLokiHandler returns a handler pushing to the Loki instance at url.
+func newLokiHandler(config Config, opts *slog.HandlerOptions) (*lokiHandler, error) {
+	if len(config.LokiLabelAttrs) > maxLokiLabelAttrs {
+		return nil, fmt.Errorf("too many Loki label attributes: %d, at most %d allowed",
+			len(config.LokiLabelAttrs), maxLokiLabelAttrs)
+	}
+
+	labels := map[string]string{}
+	for k, v := range config.LokiLabels {
+		labels[lokiLabelName(k)] = v
+	}
+	labelKeys := map[string]bool{}
+	for _, k := range config.LokiLabelAttrs {
+		labelKeys[k] = true
+	}
+
+	batchSize, batchWait := batchSettings(config, config.LokiBatchSize, config.LokiBatchWait)
+	enc := &lokiEncoder{}
+	return &lokiHandler{
+		json:      slog.NewJSONHandler(enc, opts),
+		enc:       enc,
+		labelKeys: labelKeys,
+		labels:    labels,
+		client:    newLokiClient(strings.TrimSuffix(config.LokiURL, "/")+lokiPushPath, batchSize, batchWait),
+	}, nil
+}
+
+// Enabled determines if the handler is enabled for the given log level.
+func (h *lokiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.json.Enabled(ctx, level)
+}
+
+// Handle encodes the record and queues it for the next push.
+func (h *lokiHandler) Handle(ctx context.Context, record slog.Record) error {
+	labels := make(map[string]string, len(h.labels)+len(h.labelKeys)+1)
+	for k, v := range h.labels {
+		labels[k] = v
+	}
+	labels[slog.LevelKey] = strings.ToLower(levelName(record.Level))
+	if !h.grouped {
+		record.Attrs(func(a slog.Attr) bool {
+			if h.labelKeys[a.Key] {
+				labels[lokiLabelName(a.Key)] = a.Value.Resolve().String()
+			}
+			return true
+		})
+	}
+
+	h.enc.mu.Lock()
+	h.enc.buf.Reset()
+	err := h.json.Handle(ctx, record)
+	line := strings.TrimSuffix(h.enc.buf.String(), "\n")
+	h.enc.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	h.client.enqueue(lokiEntry{labels: labels, time: record.Time, line: line})
+	return nil
+}
+
+// WithAttrs returns a new handler with the given attributes. Attributes
+// configured as labels become labels of all records logged through it.
+func (h *lokiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.json = h.json.WithAttrs(attrs)
+	if !h.grouped {
+		h2.labels = make(map[string]string, len(h.labels))
+		for k, v := range h.labels {
+			h2.labels[k] = v
+		}
+		for _, a := range attrs {
+			if h.labelKeys[a.Key] {
+				h2.labels[lokiLabelName(a.Key)] = a.Value.Resolve().String()
+			}
+		}
+	}
+	return &h2
+}
+
+// WithGroup returns a new handler with the given group name. Attributes
+// inside a group never become labels.
+func (h *lokiHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.json = h.json.WithGroup(name)
+	h2.grouped = true
+	return &h2
+}
+
+// Close pushes the queued records and stops the background goroutine.
+func (h *lokiHandler) Close() error {
+	return h.client.Close()
+}
+
+// lokiLabelName converts key into a valid Loki label name.
+func lokiLabelName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// lokiEntry is a single encoded record with its labels.
+type lokiEntry struct {
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+// lokiClient batches entries and pushes them to Loki in the background.
+type lokiClient struct {
+	*batcher[lokiEntry]
+	url  string
+	http *http.Client
+}
+
+func newLokiClient(url string, batchSize int, batchWait time.Duration) *lokiClient {
+	c := &lokiClient{
+		url:  url,
+		http: &http.Client{Timeout: 10 * time.Second},
+	}
+	c.batcher = newBatcher(batchSize, batchWait, c.push)
+	return c
+}
+
+// lokiStream is a stream in Loki's push API.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// push sends a batch, retrying as postBatch does.
+func (c *lokiClient) push(batch []lokiEntry) {
+	streams := map[string]*lokiStream{}
+	var order []string
+	for _, e := range batch {
+		key := lokiStreamKey(e.labels)
+		s, ok := streams[key]
+		if !ok {
+			s = &lokiStream{Stream: e.labels}
+			streams[key] = s
+			order = append(order, key)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+	}
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: encoding Loki batch: %v\n", err)
+		return
+	}
+
+	if err := postBatch(c.http, c.url, "loki", body); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: dropping %d records after failed Loki push: %v\n", len(batch), err)
+	}
+}
+
+// lokiStreamKey returns a canonical key for a label set.
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
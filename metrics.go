@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Metrics counts the records emitted by a logger, to export them as
+// counters, such as a Prometheus counter vector or StatsD counters.
+type Metrics interface {
+	// Count is called once for each record, other than those the logger
+	// writes itself, before it is sampled, with its level and the values of
+	// the MetricsLabels attributes it carries. It must be safe for
+	// concurrent use and should not block.
+	Count(ctx context.Context, level slog.Level, labels map[string]string)
+}
+
+// discardHandler drops the records, while being enabled at its level so
+// that they are still counted in metrics-only mode.
+type discardHandler struct {
+	level slog.Level
+}
+
+func (h discardHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h discardHandler) Handle(context.Context, slog.Record) error { return nil }
+
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h discardHandler) WithGroup(string) slog.Handler { return h }
+
+// count passes the record to the metrics, with the values of the label
+// attributes found in the record or added with WithAttrs. Labels missing
+// from the record are passed as empty strings, so that every count has the
+// same labels.
+func (h *rootHandler) count(ctx context.Context, record slog.Record) {
+	labels := make(map[string]string, len(h.metricsLabels))
+	for _, key := range h.metricsLabels {
+		labels[key] = ""
+	}
+	set := func(key string, v any) {
+		if _, ok := labels[key]; ok {
+			labels[key] = fmt.Sprint(v)
+		}
+	}
+
+	if len(h.metricsLabels) > 0 {
+		var prefix string
+		for _, goa := range h.goas {
+			if goa.group != "" {
+				prefix += goa.group + "."
+				continue
+			}
+			for _, a := range goa.attrs {
+				eachFlatAttr(prefix, a, set)
+			}
+		}
+		record.Attrs(func(a slog.Attr) bool {
+			eachFlatAttr(prefix, a, set)
+			return true
+		})
+	}
+	h.metrics.Count(ctx, record.Level, labels)
+}
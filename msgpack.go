@@ -0,0 +1,355 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// msgpackHandler is a slog.Handler that writes each record as a MessagePack
+// map, with the same fields as the JSON handler: time, level, msg, source
+// and the attributes, groups being nested maps. Records are written back to
+// back, each in a single call, which MessagePack stream decoders read
+// without any framing.
+type msgpackHandler struct {
+	opts slog.HandlerOptions
+	goas []groupOrAttrs // added with WithGroup and WithAttrs, in order
+	mu   *sync.Mutex
+	w    io.Writer
+}
+
+// newMsgpackHandler returns a msgpackHandler writing to w.
+func newMsgpackHandler(w io.Writer, opts *slog.HandlerOptions) *msgpackHandler {
+	h := &msgpackHandler{mu: &sync.Mutex{}, w: w}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled reports whether the level is at or above the configured minimum.
+func (h *msgpackHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle writes the record as a MessagePack map.
+func (h *msgpackHandler) Handle(_ context.Context, record slog.Record) error {
+	builtin := []slog.Attr{
+		slog.Time(slog.TimeKey, record.Time),
+		slog.Any(slog.LevelKey, record.Level),
+		slog.String(slog.MessageKey, record.Message),
+	}
+	if record.Time.IsZero() {
+		builtin = builtin[1:]
+	}
+	if h.opts.AddSource && record.PC != 0 {
+		if src := recordSource(record); src != nil {
+			builtin = append(builtin, slog.Any(slog.SourceKey, src))
+		}
+	}
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs = h.replace(nil, append(builtin, nestAttrs(h.goas, attrs)...))
+
+	var e msgpackEncoder
+	e.attrs(attrs)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(e.buf)
+	return err
+}
+
+// WithAttrs returns a new handler with the given attributes.
+func (h *msgpackHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{attrs: attrs})
+	return &h2
+}
+
+// WithGroup returns a new handler with the given group name.
+func (h *msgpackHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], groupOrAttrs{group: name})
+	return &h2
+}
+
+// replace resolves attrs and applies ReplaceAttr to them, recursively within
+// groups, dropping empty keys and groups, and inlining groups without keys.
+func (h *msgpackHandler) replace(groups []string, attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			sub := groups
+			if a.Key != "" {
+				sub = append(groups[:len(groups):len(groups)], a.Key)
+			}
+			members := h.replace(sub, a.Value.Group())
+			switch {
+			case len(members) == 0:
+			case a.Key == "":
+				out = append(out, members...)
+			default:
+				out = append(out, slog.Attr{Key: a.Key, Value: slog.GroupValue(members...)})
+			}
+			continue
+		}
+
+		if h.opts.ReplaceAttr != nil {
+			a = h.opts.ReplaceAttr(groups, a)
+			a.Value = a.Value.Resolve()
+			if a.Value.Kind() == slog.KindGroup {
+				// A replacement group, such as time and local_time, is not
+				// replaced again
+				members := a.Value.Group()
+				if a.Key == "" {
+					out = append(out, members...)
+				} else if len(members) > 0 {
+					out = append(out, a)
+				}
+				continue
+			}
+		}
+		if a.Key != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// msgpackEncoder appends MessagePack encodings to a buffer.
+type msgpackEncoder struct {
+	buf []byte
+}
+
+// msgpackTimestamp is the MessagePack extension type of timestamps, -1.
+const msgpackTimestamp = 0xff
+
+// attrs encodes attrs as a map.
+func (e *msgpackEncoder) attrs(attrs []slog.Attr) {
+	e.mapHeader(len(attrs))
+	for _, a := range attrs {
+		e.string(a.Key)
+		e.value(a.Value)
+	}
+}
+
+// value encodes a resolved slog value as the JSON handler would format it:
+// durations as integer nanoseconds, errors as their message, levels as
+// their name, and other values of unknown types as their JSON encoding
+// decoded into maps, arrays and scalars.
+func (e *msgpackEncoder) value(v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		e.string(v.String())
+	case slog.KindInt64:
+		e.int(v.Int64())
+	case slog.KindUint64:
+		e.uint(v.Uint64())
+	case slog.KindFloat64:
+		e.float(v.Float64())
+	case slog.KindBool:
+		e.bool(v.Bool())
+	case slog.KindDuration:
+		e.int(int64(v.Duration()))
+	case slog.KindTime:
+		e.time(v.Time())
+	case slog.KindGroup:
+		e.attrs(v.Group())
+	default:
+		switch a := v.Any().(type) {
+		case nil:
+			e.nil()
+		case slog.Level:
+			e.string(a.String())
+		case *slog.Source:
+			e.mapHeader(3)
+			e.string("function")
+			e.string(a.Function)
+			e.string("file")
+			e.string(a.File)
+			e.string("line")
+			e.int(int64(a.Line))
+		case error:
+			if _, ok := a.(json.Marshaler); !ok {
+				e.string(a.Error())
+				return
+			}
+			e.any(a)
+		case []byte:
+			e.bytes(a)
+		default:
+			e.any(a)
+		}
+	}
+}
+
+// any encodes v through its JSON encoding, or as its string form if it
+// cannot be encoded as JSON.
+func (e *msgpackEncoder) any(v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		e.string(slog.AnyValue(v).String())
+		return
+	}
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		e.string(string(b))
+		return
+	}
+	e.generic(generic)
+}
+
+// generic encodes a value decoded from JSON.
+func (e *msgpackEncoder) generic(v any) {
+	switch v := v.(type) {
+	case nil:
+		e.nil()
+	case bool:
+		e.bool(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			e.int(int64(v))
+		} else {
+			e.float(v)
+		}
+	case string:
+		e.string(v)
+	case []any:
+		e.arrayHeader(len(v))
+		for _, item := range v {
+			e.generic(item)
+		}
+	case map[string]any:
+		e.mapHeader(len(v))
+		for k, item := range v {
+			e.string(k)
+			e.generic(item)
+		}
+	}
+}
+
+func (e *msgpackEncoder) nil() {
+	e.buf = append(e.buf, 0xc0)
+}
+
+func (e *msgpackEncoder) bool(b bool) {
+	if b {
+		e.buf = append(e.buf, 0xc3)
+	} else {
+		e.buf = append(e.buf, 0xc2)
+	}
+}
+
+func (e *msgpackEncoder) int(i int64) {
+	switch {
+	case i >= 0:
+		e.uint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd2), uint32(i))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd3), uint64(i))
+	}
+}
+
+func (e *msgpackEncoder) uint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xce), uint32(u))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcf), u)
+	}
+}
+
+func (e *msgpackEncoder) float(f float64) {
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(f))
+}
+
+func (e *msgpackEncoder) string(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xda), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdb), uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) bytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xc5), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xc6), uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) arrayHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xdc), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdd), uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) mapHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xde), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdf), uint32(n))
+	}
+}
+
+// time encodes t with the timestamp extension, in its 96-bit form, which
+// holds any time with nanosecond precision.
+func (e *msgpackEncoder) time(t time.Time) {
+	e.buf = append(e.buf, 0xc7, 12, msgpackTimestamp)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(t.Nanosecond()))
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(t.Unix()))
+}
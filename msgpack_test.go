@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Format: "msgpack", Output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(l)
+
+	at := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC)
+	l.With("service", "api").WithGroup("req").Info("served",
+		slog.Group("ints",
+			slog.Int("fix", -1),
+			slog.Int("i8", -100),
+			slog.Int("i16", -30000),
+			slog.Int("i32", -2000000000),
+			slog.Int64("i64", math.MinInt64),
+			slog.Int("u8", 200),
+			slog.Int("u32", 70000),
+		),
+		slog.Time("at", at),
+		slog.Any("labels", map[string]any{"region": "eu", "zones": []int{1, -2}}),
+		slog.Group("empty"),
+		slog.Bool("ok", true),
+		slog.Float64("ratio", 0.25),
+		slog.Duration("took", 1500*time.Millisecond),
+	)
+
+	v, rest, err := decodeMsgpack(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("%d bytes after the record", len(rest))
+	}
+	record, ok := v.(map[string]any)
+	if !ok {
+		t.Fatalf("record is %T, want a map", v)
+	}
+	if _, ok := record[slog.TimeKey].(time.Time); !ok {
+		t.Errorf("time is %T, want a timestamp extension", record[slog.TimeKey])
+	}
+	if record[slog.LevelKey] != "INFO" || record[slog.MessageKey] != "served" || record["service"] != "api" {
+		t.Errorf("unexpected built-in fields: %v", record)
+	}
+
+	want := map[string]any{
+		"ints": map[string]any{
+			"fix": int64(-1),
+			"i8":  int64(-100),
+			"i16": int64(-30000),
+			"i32": int64(-2000000000),
+			"i64": int64(math.MinInt64),
+			"u8":  int64(200),
+			"u32": int64(70000),
+		},
+		"at":     at,
+		"labels": map[string]any{"region": "eu", "zones": []any{int64(1), int64(-2)}},
+		"ok":     true,
+		"ratio":  0.25,
+		"took":   int64(1500 * time.Millisecond),
+	}
+	if got := record["req"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("req group:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+// decodeMsgpack decodes the MessagePack value at the start of b, as
+// written by msgpackEncoder, and returns it with the remaining bytes.
+// Integers are decoded as int64, maps as map[string]any and timestamps as
+// UTC times.
+func decodeMsgpack(b []byte) (any, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("unexpected end of input")
+	}
+	c, b := b[0], b[1:]
+	switch {
+	case c <= 0x7f:
+		return int64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xf0 == 0x80:
+		return decodeMsgpackMap(b, int(c&0x0f))
+	case c&0xf0 == 0x90:
+		return decodeMsgpackArray(b, int(c&0x0f))
+	case c&0xe0 == 0xa0:
+		return decodeMsgpackString(b, int(c&0x1f))
+	}
+
+	// size returns the big-endian unsigned integer of n bytes at the start
+	// of b
+	size := func(n int) (uint64, error) {
+		if len(b) < n {
+			return 0, fmt.Errorf("unexpected end of input")
+		}
+		var u uint64
+		for _, x := range b[:n] {
+			u = u<<8 | uint64(x)
+		}
+		b = b[n:]
+		return u, nil
+	}
+	widths := map[byte]int{
+		0xc4: 1, 0xc5: 2, 0xc6: 4, 0xcb: 8, 0xcc: 1, 0xcd: 2, 0xce: 4, 0xcf: 8,
+		0xd0: 1, 0xd1: 2, 0xd2: 4, 0xd3: 8, 0xd9: 1, 0xda: 2, 0xdb: 4,
+		0xdc: 2, 0xdd: 4, 0xde: 2, 0xdf: 4,
+	}
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, b, nil
+	case 0xc7:
+		if len(b) < 14 || b[0] != 12 || b[1] != msgpackTimestamp {
+			return nil, nil, fmt.Errorf("unsupported extension")
+		}
+		nsec := binary.BigEndian.Uint32(b[2:6])
+		sec := int64(binary.BigEndian.Uint64(b[6:14]))
+		return time.Unix(sec, int64(nsec)).UTC(), b[14:], nil
+	}
+	width, ok := widths[c]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported type 0x%02x", c)
+	}
+	u, err := size(width)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch c {
+	case 0xc4, 0xc5, 0xc6:
+		if uint64(len(b)) < u {
+			return nil, nil, fmt.Errorf("unexpected end of input")
+		}
+		return bytes.Clone(b[:u]), b[u:], nil
+	case 0xcb:
+		return math.Float64frombits(u), b, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return int64(u), b, nil
+	case 0xd0:
+		return int64(int8(u)), b, nil
+	case 0xd1:
+		return int64(int16(u)), b, nil
+	case 0xd2:
+		return int64(int32(u)), b, nil
+	case 0xd3:
+		return int64(u), b, nil
+	case 0xd9, 0xda, 0xdb:
+		return decodeMsgpackString(b, int(u))
+	case 0xdc, 0xdd:
+		return decodeMsgpackArray(b, int(u))
+	default:
+		return decodeMsgpackMap(b, int(u))
+	}
+}
+
+func decodeMsgpackString(b []byte, n int) (any, []byte, error) {
+	if len(b) < n {
+		return nil, nil, fmt.Errorf("unexpected end of input")
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func decodeMsgpackArray(b []byte, n int) (any, []byte, error) {
+	out := make([]any, n)
+	for i := range out {
+		var err error
+		if out[i], b, err = decodeMsgpack(b); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, b, nil
+}
+
+func decodeMsgpackMap(b []byte, n int) (any, []byte, error) {
+	out := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		k, rest, err := decodeMsgpack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("map key is %T, want a string", k)
+		}
+		var v any
+		if v, b, err = decodeMsgpack(rest); err != nil {
+			return nil, nil, err
+		}
+		out[key] = v
+	}
+	return out, b, nil
+}
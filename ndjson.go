@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ndjsonWriter enforces that each JSON record is written as a single valid
+// line ending in a single newline: raw line breaks within strings are
+// escaped and those between tokens are replaced with spaces. A record that
+// is still not valid JSON is replaced with an error record embedding it as
+// a string. If flush is set, it is called after each record.
+type ndjsonWriter struct {
+	w     io.Writer
+	flush func() error
+}
+
+// newNDJSONWriter returns an ndjsonWriter for the output of the
+// configuration, or an error if its records are not JSON.
+func newNDJSONWriter(w io.Writer, config Config, flush func() error) (*ndjsonWriter, error) {
+	if format := outputFormat(config); format != "" && format != "json" && format != "gcp" {
+		return nil, fmt.Errorf("strict NDJSON is not supported with format %q", format)
+	}
+	return &ndjsonWriter{w: w, flush: flush}, nil
+}
+
+// Write writes the record p as a single line.
+func (n *ndjsonWriter) Write(p []byte) (int, error) {
+	line := ndjsonLine(p)
+	if !json.Valid(line) {
+		original, _ := json.Marshal(string(bytes.TrimRight(p, "\n")))
+		line = append([]byte(`{"level":"ERROR","msg":"invalid log record","record":`), original...)
+		line = append(line, '}')
+	}
+	if _, err := n.w.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	if n.flush != nil {
+		if err := n.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// ndjsonLine returns the record p as a single line, without a trailing
+// newline.
+func ndjsonLine(p []byte) []byte {
+	p = bytes.TrimRight(p, " \t\r\n")
+	if bytes.IndexAny(p, "\r\n") < 0 {
+		return bytes.Clone(p)
+	}
+
+	line := make([]byte, 0, len(p)+8)
+	inString, escaped := false, false
+	for _, c := range p {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case c == '\n' || c == '\r':
+			if !inString {
+				line = append(line, ' ')
+			} else if c == '\n' {
+				line = append(line, `\n`...)
+			} else {
+				line = append(line, `\r`...)
+			}
+			continue
+		}
+		line = append(line, c)
+	}
+	return line
+}
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+	tests := []struct {
+		name, record, want string
+	}{
+		{name: "single line", record: "{\"msg\":\"ok\"}\n", want: "{\"msg\":\"ok\"}\n"},
+		{name: "between tokens", record: "{\n  \"msg\": \"ok\"\n}\n\n", want: "{   \"msg\": \"ok\" }\n"},
+		{name: "in string", record: "{\"msg\":\"a\nb\r\"}", want: "{\"msg\":\"a\\nb\\r\"}\n"},
+		{name: "escaped quote", record: "{\"msg\":\"\\\"\n\"}", want: "{\"msg\":\"\\\"\\n\"}\n"},
+		{name: "invalid", record: "{\"msg\":\n", want: "{\"level\":\"ERROR\",\"msg\":\"invalid log record\",\"record\":\"{\\\"msg\\\":\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			flushes := 0
+			w := &ndjsonWriter{w: &buf, flush: func() error { flushes++; return nil }}
+			n, err := w.Write([]byte(tt.record))
+			if err != nil || n != len(tt.record) {
+				t.Fatalf("Write = %d, %v, want %d, nil", n, err, len(tt.record))
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+			if !json.Valid(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))) {
+				t.Errorf("wrote invalid JSON %q", buf.String())
+			}
+			if flushes != 1 {
+				t.Errorf("flushed %d times, want once", flushes)
+			}
+		})
+	}
+}
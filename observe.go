@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Observe runs fn and logs its outcome under the given operation name.
+//
+// It logs "operation started" at debug level before calling fn, then
+// "operation completed" at info level, or "operation failed" at error level
+// if fn returns an error. All records carry an "operation" attribute with
+// name; the completion record also carries "duration", and "error" when fn
+// fails. Failures reach Sentry through the logger's Sentry integration like
+// any other error record.
+//
+// When Sentry tracing is enabled, fn runs inside a span with operation
+// "function" and description name, whose status reflects the result. No
+// span is started if ctx is already canceled or expired. A trace extracted
+// with ExtractContext is continued in a new transaction. The span is always
+// sampled if ctx is marked with ForceTrace.
+//
+// The records report the caller of Observe as their source, by skipping one
+// frame; see WithCallerSkip.
+//
+// The error returned by fn is returned unchanged.
+func Observe(ctx context.Context, logger Logger, name string, fn func() error) error {
+	logger = WithCallerSkip(logger, 1).With(slog.String("operation", name))
+
+	var span *sentry.Span
+	if tracingEnabled(ctx) && !contextDone(ctx) {
+		opts := []sentry.SpanOption{sentry.WithDescription(name)}
+		if sentry.SpanFromContext(ctx) == nil {
+			if remote, _, _, _, ok := remoteTraceFromContext(ctx); ok {
+				opts = append(opts, sentry.ContinueFromHeaders(remote.header, remote.baggage))
+			}
+		}
+		if traceForced(ctx) {
+			opts = append(opts, sentry.WithSpanSampled(sentry.SampledTrue))
+		}
+		span = sentry.StartSpan(ctx, "function", opts...)
+		ctx = span.Context()
+	}
+
+	logger.DebugContext(ctx, "operation started")
+	start := time.Now()
+	err := fn()
+	duration := time.Since(start)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "operation failed",
+			slog.Duration("duration", duration),
+			slog.Any("error", err),
+		)
+	} else {
+		logger.InfoContext(ctx, "operation completed", slog.Duration("duration", duration))
+	}
+
+	if span != nil {
+		if err != nil {
+			span.Status = sentry.SpanStatusInternalError
+		} else {
+			span.Status = sentry.SpanStatusOK
+		}
+		span.Finish()
+	}
+
+	return err
+}
+
+// tracingEnabled reports whether the Sentry hub for ctx has tracing enabled.
+func tracingEnabled(ctx context.Context) bool {
+	client := hubFromContext(ctx).Client()
+	return client != nil && client.Options().EnableTracing
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+)
+
+// panicLogger reports panics recovered while handling records. It writes
+// to stderr directly, bypassing the handlers that may have panicked.
+var panicLogger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+// recoverPanic recovers a panic raised while handling record, reports it
+// on stderr with the record's redacted message, and sets *err to an error
+// describing it. It must be deferred directly.
+func (h *rootHandler) recoverPanic(record slog.Record, err *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	msg := record.Message
+	if h.redactor != nil {
+		msg = h.redactor.mask(msg)
+	}
+	panicLogger.LogAttrs(context.Background(), slog.LevelError, "log handler panicked",
+		slog.String(logTypeKey, "handler_panic"),
+		slog.String("panic", fmt.Sprint(v)),
+		slog.String("record_level", levelName(record.Level)),
+		slog.String("record_msg", msg),
+		slog.String("stack", string(debug.Stack())),
+	)
+	*err = fmt.Errorf("log handler panicked: %v", v)
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// piiDetector finds one kind of personal data in text. Matches of re are
+// masked if valid accepts them, which rules out most random numbers.
+type piiDetector struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// piiDetectors are the built-in detectors, in the order they are applied:
+// card numbers before phone numbers, which could match part of them.
+var piiDetectors = []piiDetector{
+	{
+		name: "email",
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	{
+		name:  "card",
+		re:    regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid: luhnValid,
+	},
+	{
+		name:  "ssn",
+		re:    regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		valid: ssnValid,
+	},
+	{
+		name: "phone",
+		re:   regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\) ?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b`),
+	},
+}
+
+// newPIIDetectors returns the detectors with the given names, in the order
+// they are applied. "all" selects every detector.
+func newPIIDetectors(names []string) ([]piiDetector, error) {
+	enabled := map[string]bool{}
+	for _, name := range names {
+		if name == "all" {
+			return piiDetectors, nil
+		}
+		found := false
+		for _, d := range piiDetectors {
+			if d.name == name {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported PII detector %q", name)
+		}
+		enabled[name] = true
+	}
+
+	var detectors []piiDetector
+	for _, d := range piiDetectors {
+		if enabled[d.name] {
+			detectors = append(detectors, d)
+		}
+	}
+	return detectors, nil
+}
+
+// mask replaces the valid matches in s.
+func (d piiDetector) mask(s string) string {
+	if d.valid == nil {
+		return d.re.ReplaceAllString(s, redactedValue)
+	}
+	return d.re.ReplaceAllStringFunc(s, func(m string) string {
+		if d.valid(m) {
+			return redactedValue
+		}
+		return m
+	})
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum of
+// payment card numbers.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// ssnValid reports whether s, formatted as AAA-GG-SSSS, is a number the
+// Social Security Administration could have issued.
+func ssnValid(s string) bool {
+	area, group, serial := s[0:3], s[4:6], s[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// quietHours is a daily window during which only errors are sent to
+// Sentry, warnings being recorded as breadcrumbs instead.
+type quietHours struct {
+	start, end int // minutes since midnight; end is exclusive
+	loc        *time.Location
+}
+
+// newQuietHours parses a "HH:MM-HH:MM" window in the named time zone, the
+// local one if empty. It returns nil if spec is empty. Windows ending
+// before they start span midnight.
+func newQuietHours(spec, zone string) (*quietHours, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid Sentry quiet hours %q: want HH:MM-HH:MM", spec)
+	}
+	q := &quietHours{loc: time.Local}
+	var err error
+	if q.start, err = parseClock(from); err == nil {
+		q.end, err = parseClock(to)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry quiet hours %q: %w", spec, err)
+	}
+	if q.start == q.end {
+		return nil, fmt.Errorf("invalid Sentry quiet hours %q: empty window", spec)
+	}
+	if zone != "" {
+		if q.loc, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("invalid Sentry quiet hours time zone: %w", err)
+		}
+	}
+	return q, nil
+}
+
+// parseClock parses a "HH:MM" time of day into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether t falls within the quiet hours, in their time
+// zone.
+func (q *quietHours) contains(t time.Time) bool {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	t = t.In(q.loc)
+	m := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// quieted reports whether a record of the level logged at t is held back
+// from Sentry by the quiet hours, if any: records below error during them.
+func (q *quietHours) quieted(level slog.Level, t time.Time) bool {
+	return q != nil && level < slog.LevelError && q.contains(t)
+}
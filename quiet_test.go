@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	q, err := newQuietHours("22:30-06:00", "Europe/Paris")
+	if err != nil {
+		t.Skipf("loading time zone: %v", err)
+	}
+	paris := q.loc
+	tests := []struct {
+		at    time.Time
+		level slog.Level
+		want  bool
+	}{
+		{at: time.Date(2024, 1, 10, 22, 29, 0, 0, paris), level: slog.LevelWarn, want: false},
+		{at: time.Date(2024, 1, 10, 22, 30, 0, 0, paris), level: slog.LevelWarn, want: true},
+		{at: time.Date(2024, 1, 11, 3, 0, 0, 0, paris), level: slog.LevelInfo, want: true},
+		{at: time.Date(2024, 1, 11, 5, 59, 0, 0, paris), level: slog.LevelWarn, want: true},
+		{at: time.Date(2024, 1, 11, 6, 0, 0, 0, paris), level: slog.LevelWarn, want: false},
+		{at: time.Date(2024, 1, 11, 3, 0, 0, 0, paris), level: slog.LevelError, want: false},
+		// 23:00 in Paris is 22:00 UTC in winter
+		{at: time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC), level: slog.LevelWarn, want: true},
+	}
+	for _, tt := range tests {
+		if got := q.quieted(tt.level, tt.at); got != tt.want {
+			t.Errorf("quieted(%s, %s) = %t, want %t", tt.level, tt.at, got, tt.want)
+		}
+	}
+
+	var none *quietHours
+	if none.quieted(slog.LevelWarn, time.Now()) {
+		t.Error("nil quiet hours quieted a record")
+	}
+}
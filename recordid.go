@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+const (
+	// recordIDKey is the attribute holding the ID of a record.
+	recordIDKey = "record_id"
+	// causedByKey is the attribute holding the IDs of the records that led
+	// to a record.
+	causedByKey = "caused_by"
+)
+
+// NewRecordID returns a new random record ID, 32 hexadecimal digits, to be
+// logged with RecordID and referenced by later records with CausedBy.
+func NewRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RecordID returns an attribute setting the ID of the record it is logged
+// with, as "record_id", instead of the one IncludeRecordID generates.
+func RecordID(id string) slog.Attr {
+	return slog.String(recordIDKey, id)
+}
+
+// CausedBy returns an attribute linking the record it is logged with to
+// the prior records of the given IDs, as a "caused_by" list, so that the
+// steps of an operation form a chain consumers can follow back:
+//
+//	id := logger.NewRecordID()
+//	l.Info("order received", logger.RecordID(id))
+//	...
+//	l.Info("payment captured", logger.CausedBy(id))
+func CausedBy(ids ...string) slog.Attr {
+	return slog.Any(causedByKey, ids)
+}
+
+// splitRecordID removes the record ID attributes from attrs and returns
+// the remaining attributes and the last ID, or id if attrs has none.
+func splitRecordID(attrs []slog.Attr, id string) ([]slog.Attr, string) {
+	n := 0
+	for _, a := range attrs {
+		if isRecordID(a) {
+			n++
+		}
+	}
+	if n == 0 {
+		return attrs, id
+	}
+
+	kept := make([]slog.Attr, 0, len(attrs)-n)
+	for _, a := range attrs {
+		if isRecordID(a) {
+			id = a.Value.String()
+		} else {
+			kept = append(kept, a)
+		}
+	}
+	return kept, id
+}
+
+// recordIDRecord removes the record ID attributes from the record and
+// returns it with the last ID, or id if the record has none.
+func recordIDRecord(record slog.Record, id string) (slog.Record, string) {
+	found := false
+	record.Attrs(func(a slog.Attr) bool {
+		found = isRecordID(a)
+		return !found
+	})
+	if !found {
+		return record, id
+	}
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs, id = splitRecordID(attrs, id)
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(attrs...)
+	return out, id
+}
+
+// isRecordID reports whether a is a record ID, as returned by RecordID.
+func isRecordID(a slog.Attr) bool {
+	return a.Key == recordIDKey && a.Value.Kind() == slog.KindString
+}
@@ -0,0 +1,370 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	// redactedValue replaces the value of every redacted attribute.
+	redactedValue = "[REDACTED]"
+	// defaultRedactMaxDepth bounds how deep redaction descends into values.
+	defaultRedactMaxDepth = 8
+)
+
+// redactRules are the redaction rules of a logger, from Config or a policy
+// file.
+type redactRules struct {
+	// Keys are redacted wherever they appear, case-insensitively.
+	Keys []string `json:"keys"`
+	// Patterns are regular expressions whose matches in string values and
+	// messages are masked.
+	Patterns []string `json:"patterns"`
+	// Paths are dot-separated paths whose values are redacted.
+	Paths []string `json:"paths"`
+	// MaxDepth bounds how deep redaction descends into values.
+	MaxDepth int `json:"max_depth"`
+	// PII names the built-in detectors of personal data whose matches in
+	// string values and messages are masked.
+	PII []string `json:"pii"`
+}
+
+// loadRedactRules reads redaction rules from a JSON policy file.
+func loadRedactRules(path string) (redactRules, error) {
+	var rules redactRules
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("reading redaction policy: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return rules, fmt.Errorf("parsing redaction policy %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// merge returns the union of both rule sets. The larger max depth wins.
+func (r redactRules) merge(other redactRules) redactRules {
+	return redactRules{
+		Keys:     append(r.Keys[:len(r.Keys):len(r.Keys)], other.Keys...),
+		Patterns: append(r.Patterns[:len(r.Patterns):len(r.Patterns)], other.Patterns...),
+		Paths:    append(r.Paths[:len(r.Paths):len(r.Paths)], other.Paths...),
+		MaxDepth: max(r.MaxDepth, other.MaxDepth),
+		PII:      append(r.PII[:len(r.PII):len(r.PII)], other.PII...),
+	}
+}
+
+// redactor replaces sensitive values with redactedValue.
+//
+// A path is a dot-separated list of keys, starting with the attribute key
+// (prefixed by any group names) and continuing into map keys, JSON object
+// fields or struct fields. A "*" segment matches any single key. Keys are
+// matched at any depth, and patterns mask matching substrings of every
+// string value and of the message, as do PII detectors.
+type redactor struct {
+	paths    [][]string
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+	pii      []piiDetector
+	maxDepth int
+}
+
+// newRedactor validates rules and builds a redactor from them. It returns
+// nil if there is nothing to redact.
+func newRedactor(rules redactRules) (*redactor, error) {
+	if len(rules.Paths) == 0 && len(rules.Keys) == 0 && len(rules.Patterns) == 0 && len(rules.PII) == 0 {
+		return nil, nil
+	}
+
+	r := &redactor{maxDepth: rules.MaxDepth}
+	if r.maxDepth <= 0 {
+		r.maxDepth = defaultRedactMaxDepth
+	}
+
+	for _, p := range rules.Paths {
+		segs := strings.Split(p, ".")
+		for _, s := range segs {
+			if s == "" {
+				return nil, fmt.Errorf("invalid redact path %q: empty segment", p)
+			}
+		}
+		if len(segs) > r.maxDepth {
+			return nil, fmt.Errorf("invalid redact path %q: deeper than max depth %d", p, r.maxDepth)
+		}
+		r.paths = append(r.paths, segs)
+	}
+
+	if len(rules.Keys) > 0 {
+		r.keys = make(map[string]bool, len(rules.Keys))
+		for _, k := range rules.Keys {
+			if k == "" {
+				return nil, fmt.Errorf("invalid redact key: empty")
+			}
+			r.keys[strings.ToLower(k)] = true
+		}
+	}
+
+	for _, p := range rules.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	pii, err := newPIIDetectors(rules.PII)
+	if err != nil {
+		return nil, err
+	}
+	r.pii = pii
+	return r, nil
+}
+
+// deep reports whether values must be traversed regardless of paths.
+func (r *redactor) deep() bool {
+	return len(r.keys) > 0 || r.masks()
+}
+
+// masks reports whether string values are masked.
+func (r *redactor) masks() bool {
+	return len(r.patterns) > 0 || len(r.pii) > 0
+}
+
+func (r *redactor) redactsKey(key string) bool {
+	return r.keys != nil && r.keys[strings.ToLower(key)]
+}
+
+// pathsFor returns the remaining paths that apply to attributes logged
+// inside the given groups. It reports whether a whole group is redacted.
+func (r *redactor) pathsFor(groups []string) ([][]string, bool) {
+	paths := r.paths
+	for _, g := range groups {
+		if r.redactsKey(g) {
+			return nil, true
+		}
+		var all bool
+		paths, all = descend(paths, g)
+		if all {
+			return nil, true
+		}
+	}
+	return paths, false
+}
+
+// record returns a copy of rec with its message masked and its attributes
+// redacted.
+func (r *redactor) record(groups []string, rec slog.Record) slog.Record {
+	paths, all := r.pathsFor(groups)
+	if len(paths) == 0 && !all && !r.deep() {
+		return rec
+	}
+
+	out := slog.NewRecord(rec.Time, rec.Level, r.mask(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		if all {
+			out.AddAttrs(slog.String(a.Key, redactedValue))
+		} else {
+			out.AddAttrs(r.attr(paths, a, 1))
+		}
+		return true
+	})
+	return out
+}
+
+// attrs redacts attributes logged inside the given groups.
+func (r *redactor) attrs(groups []string, attrs []slog.Attr) []slog.Attr {
+	paths, all := r.pathsFor(groups)
+	if len(paths) == 0 && !all && !r.deep() {
+		return attrs
+	}
+
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		if all {
+			out[i] = slog.String(a.Key, redactedValue)
+		} else {
+			out[i] = r.attr(paths, a, 1)
+		}
+	}
+	return out
+}
+
+// attr redacts an attribute nested depth levels deep, an attribute of the
+// record being at depth 1. Attributes deeper than the max depth are left
+// as they are.
+func (r *redactor) attr(paths [][]string, a slog.Attr, depth int) slog.Attr {
+	if depth > r.maxDepth {
+		return a
+	}
+	tails, all := descend(paths, a.Key)
+	if all || r.redactsKey(a.Key) {
+		return slog.String(a.Key, redactedValue)
+	}
+	if len(tails) == 0 && !r.deep() {
+		return a
+	}
+	if b, ok := a.Value.Any().(*bodyValue); ok {
+		return slog.Any(a.Key, r.redactBody(tails, b, depth))
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		out := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			out[i] = r.attr(tails, ga, depth+1)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(out...)}
+	case slog.KindString:
+		s := v.String()
+		if !looksLikeJSON(s) || (len(tails) == 0 && len(r.keys) == 0) {
+			return slog.String(a.Key, r.mask(s))
+		}
+		var decoded any
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return slog.String(a.Key, r.mask(s))
+		}
+		encoded, err := json.Marshal(r.value(tails, decoded, depth))
+		if err != nil {
+			return a
+		}
+		return slog.String(a.Key, string(encoded))
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			if r.masks() {
+				return slog.String(a.Key, r.mask(err.Error()))
+			}
+			return a
+		}
+		if !isContainer(v.Any()) {
+			return a
+		}
+		switch g := toGeneric(v.Any()).(type) {
+		case map[string]any, []any:
+			return slog.Any(a.Key, r.value(tails, g, depth))
+		}
+		return a
+	default:
+		return a
+	}
+}
+
+// value redacts a generic JSON-like value as produced by encoding/json,
+// held by a key at the given depth. Arrays are traversed transparently: a
+// path applies to each element, at the depth of the array. Structs and
+// typed maps nested in a map built by the caller are converted to the
+// generic form first.
+func (r *redactor) value(paths [][]string, v any, depth int) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if depth+1 > r.maxDepth {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			tails, all := descend(paths, k)
+			switch {
+			case all || r.redactsKey(k):
+				out[k] = redactedValue
+			case len(tails) > 0 || r.deep():
+				out[k] = r.value(tails, val, depth+1)
+			default:
+				out[k] = val
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = r.value(paths, val, depth)
+		}
+		return out
+	case string:
+		return r.mask(v)
+	default:
+		if isContainer(v) {
+			switch g := toGeneric(v).(type) {
+			case map[string]any, []any:
+				return r.value(paths, g, depth)
+			}
+		}
+		return v
+	}
+}
+
+// mask replaces the substrings of s matching any pattern, then those found
+// by PII detectors.
+func (r *redactor) mask(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	for _, d := range r.pii {
+		s = d.mask(s)
+	}
+	return s
+}
+
+// descend consumes key from the head of each path. It reports the remaining
+// tails and whether any path ends at key, meaning its value is redacted.
+func descend(paths [][]string, key string) ([][]string, bool) {
+	var tails [][]string
+	for _, p := range paths {
+		if p[0] != key && p[0] != "*" {
+			continue
+		}
+		if len(p) == 1 {
+			return nil, true
+		}
+		tails = append(tails, p[1:])
+	}
+	return tails, false
+}
+
+// isContainer reports whether v is a map, slice, array or struct, possibly
+// behind pointers, whose contents can be redacted.
+func isContainer(v any) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// toGeneric converts maps, slices and structs to the generic form produced
+// by encoding/json so they can be traversed. Values that cannot be encoded
+// are returned unchanged.
+func toGeneric(v any) any {
+	switch v.(type) {
+	case map[string]any, []any:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func looksLikeJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) > 1 && (s[0] == '{' || s[0] == '[')
+}
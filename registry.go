@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// registeredKey is a context key whose value is logged under attr.
+type registeredKey struct {
+	attr string
+	key  any
+}
+
+var (
+	registryMu sync.Mutex
+	// registry holds the registered keys. It is replaced on each
+	// registration, so records read it without locking.
+	registry atomic.Pointer[[]registeredKey]
+)
+
+// RegisterContextKey registers a context key whose value, when present in
+// the context a record is logged with, is added to the record as an
+// attribute named attr. It is meant for keys defined by other packages;
+// prefer NewContextKey for keys of your own. Registering a key again
+// changes its attribute name. Registration applies to all loggers, and is
+// usually done from init functions.
+func RegisterContextKey(attr string, key any) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	var keys []registeredKey
+	if old := registry.Load(); old != nil {
+		keys = append(keys, *old...)
+	}
+	for i, k := range keys {
+		if k.key == key {
+			keys[i].attr = attr
+			registry.Store(&keys)
+			return
+		}
+	}
+	keys = append(keys, registeredKey{attr: attr, key: key})
+	registry.Store(&keys)
+}
+
+// ContextKey is a typed context key whose values are logged, created with
+// NewContextKey.
+type ContextKey[T any] struct {
+	attr string
+}
+
+// NewContextKey returns a new context key for values of type T, registered
+// so that its value is logged under attr with every record logged with a
+// context carrying it.
+//
+//	var tenantKey = logger.NewContextKey[string]("tenant")
+//
+//	ctx = tenantKey.WithValue(ctx, "acme")
+//	l.InfoContext(ctx, "invoice sent") // ... "tenant":"acme"
+func NewContextKey[T any](attr string) *ContextKey[T] {
+	k := &ContextKey[T]{attr: attr}
+	RegisterContextKey(attr, k)
+	return k
+}
+
+// WithValue returns a copy of ctx carrying v under the key.
+func (k *ContextKey[T]) WithValue(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value returns the value of the key in ctx, and whether there is one.
+func (k *ContextKey[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// registeredContextAttrs adds the values of the registered context keys in
+// ctx to the record. Attributes already on the record take precedence.
+func registeredContextAttrs(ctx context.Context, record slog.Record) slog.Record {
+	keys := registry.Load()
+	if ctx == nil || keys == nil {
+		return record
+	}
+
+	var found []slog.Attr
+	for _, k := range *keys {
+		if v := ctx.Value(k.key); v != nil {
+			found = append(found, slog.Any(k.attr, v))
+		}
+	}
+	if len(found) == 0 {
+		return record
+	}
+
+	present := make(map[string]bool, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		present[a.Key] = true
+		return true
+	})
+	record = record.Clone()
+	for _, a := range found {
+		if !present[a.Key] {
+			record.AddAttrs(a)
+		}
+	}
+	return record
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+)
+
+// localTimeKey is the attribute holding the local time of a record.
+const localTimeKey = "local_time"
+
+// localTimeLayout is the layout of the local_time attribute: RFC 3339 with
+// milliseconds and the numeric zone offset.
+const localTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// replaceAttrFunc has the signature of slog.HandlerOptions.ReplaceAttr.
+type replaceAttrFunc func(groups []string, a slog.Attr) slog.Attr
+
+// chainReplaceAttr returns a ReplaceAttr function applying fns in order, or
+// nil if there are none.
+func chainReplaceAttr(fns ...replaceAttrFunc) replaceAttrFunc {
+	var chain []replaceAttrFunc
+	for _, fn := range fns {
+		if fn != nil {
+			chain = append(chain, fn)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		for _, fn := range chain {
+			a = fn(groups, a)
+			if a.Key == "" && a.Value.Kind() != slog.KindGroup {
+				return a
+			}
+		}
+		return a
+	}
+}
+
+// localTimeReplacer converts the built-in time to UTC and adds the local
+// time next to it, by replacing the time attribute with an inlined group.
+// The UTC time is formatted as handlers format times, so that the group
+// members do not match again when ReplaceAttr is applied to them.
+func localTimeReplacer(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || a.Key != slog.TimeKey || a.Value.Kind() != slog.KindTime {
+		return a
+	}
+	t := a.Value.Time()
+	return slog.Attr{Value: slog.GroupValue(
+		slog.String(slog.TimeKey, t.UTC().Format(time.RFC3339Nano)),
+		slog.String(localTimeKey, t.In(time.Local).Format(localTimeLayout)),
+	)}
+}
+
+// floatReplacer returns a replacer formatting float attributes with the
+// given number of decimals if positive, and otherwise in the shortest form
+// that round-trips, without an exponent if noExponent is set. NaN and
+// infinities become the strings "NaN", "+Inf" and "-Inf", which JSON has no
+// numbers for. It returns nil if floats keep slog's formatting.
+func floatReplacer(precision int, noExponent bool) replaceAttrFunc {
+	if precision <= 0 && !noExponent {
+		return nil
+	}
+	format, prec := byte('g'), -1
+	if noExponent {
+		format = 'f'
+	}
+	if precision > 0 {
+		format, prec = 'f', precision
+	}
+	return func(_ []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindFloat64 {
+			return a
+		}
+		f := a.Value.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return slog.String(a.Key, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		// A json.Number is written as is by the JSON handler, as a number
+		return slog.Any(a.Key, json.Number(strconv.FormatFloat(f, format, prec, 64)))
+	}
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFloatFormatting(t *testing.T) {
+	values := map[string]float64{
+		"large": 1e21,
+		"small": 1e-7,
+		"nan":   math.NaN(),
+		"inf":   math.Inf(1),
+		"ninf":  math.Inf(-1),
+	}
+	tests := []struct {
+		name      string
+		precision int
+		noExp     bool
+		want      map[string]string
+	}{
+		{
+			name:      "precision",
+			precision: 3,
+			want: map[string]string{
+				"large": `1000000000000000000000.000`,
+				"small": `0.000`,
+				"nan":   `"NaN"`,
+				"inf":   `"+Inf"`,
+				"ninf":  `"-Inf"`,
+			},
+		},
+		{
+			name:  "no exponent",
+			noExp: true,
+			want: map[string]string{
+				"large": `1000000000000000000000`,
+				"small": `0.0000001`,
+				"nan":   `"NaN"`,
+				"inf":   `"+Inf"`,
+				"ninf":  `"-Inf"`,
+			},
+		},
+		{
+			name:      "precision and no exponent",
+			precision: 9,
+			noExp:     true,
+			want: map[string]string{
+				"large": `1000000000000000000000.000000000`,
+				"small": `0.000000100`,
+				"nan":   `"NaN"`,
+				"inf":   `"+Inf"`,
+				"ninf":  `"-Inf"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := logFloats(t, Config{FloatPrecision: tt.precision, FloatNoExponent: tt.noExp}, values)
+			if !json.Valid(line) {
+				t.Fatalf("invalid JSON: %s", line)
+			}
+			var record map[string]json.RawMessage
+			if err := json.Unmarshal(line, &record); err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range tt.want {
+				if got := string(record[key]); got != want {
+					t.Errorf("%s = %s, want %s", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFloatFormattingDefault(t *testing.T) {
+	line := logFloats(t, Config{}, map[string]float64{
+		"large": 1e21,
+		"nan":   math.NaN(),
+		"inf":   math.Inf(1),
+	})
+	if !json.Valid(line) {
+		t.Fatalf("invalid JSON: %s", line)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(line, &record); err != nil {
+		t.Fatal(err)
+	}
+	if got := record["large"]; got != 1e21 {
+		t.Errorf("large = %v, want 1e21", got)
+	}
+	// Without a float option, slog writes values JSON cannot represent as
+	// error strings.
+	for _, key := range []string{"nan", "inf"} {
+		if s, _ := record[key].(string); !strings.HasPrefix(s, "!ERROR:") {
+			t.Errorf("%s = %v, want slog's !ERROR: string", key, record[key])
+		}
+	}
+}
+
+// logFloats logs values as attributes of one record through a JSON logger
+// with the float options of config, and returns the line written.
+func logFloats(t *testing.T, config Config, values map[string]float64) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	config.Format = "json"
+	config.Output = &buf
+	l, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := make([]any, 0, 2*len(values))
+	for key, v := range values {
+		args = append(args, key, v)
+	}
+	l.Info("floats", args...)
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.TrimSpace(buf.Bytes())
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// retentionKey is the key of the attribute holding the retention hint of a
+// record.
+const retentionKey = "retention"
+
+// retentionHint is the value of an attribute setting the retention hint of
+// records, formatted, before it is written as a string.
+type retentionHint string
+
+// Retention returns an attribute hinting how long the record it is logged
+// with should be kept by downstream systems, written as a "retention"
+// attribute: a number of days such as "30d", or of hours such as "36h" for
+// durations that are not whole days, rounded up. It overrides the hint of
+// the logger, and adds none if d is not positive.
+//
+//	l.Info("payment captured", logger.Retention(365*24*time.Hour))
+func Retention(d time.Duration) slog.Attr {
+	return slog.Any(retentionKey, retentionHint(formatRetention(d)))
+}
+
+// WithRetention returns a logger whose records carry a retention hint of
+// d, overriding that of l or Config.DefaultRetention. See Retention.
+func WithRetention(l Logger, d time.Duration) Logger {
+	return l.With(Retention(d))
+}
+
+// formatRetention formats d as a retention hint, or returns "" if it is
+// not positive.
+func formatRetention(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d <= 0:
+		return ""
+	case d%day == 0:
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	default:
+		return strconv.FormatInt(int64((d+time.Hour-1)/time.Hour), 10) + "h"
+	}
+}
+
+// splitRetention removes the retention hint attributes from attrs and
+// returns the remaining attributes and the last hint, or hint if attrs has
+// none.
+func splitRetention(attrs []slog.Attr, hint string) ([]slog.Attr, string) {
+	n := 0
+	for _, a := range attrs {
+		if _, ok := a.Value.Any().(retentionHint); ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return attrs, hint
+	}
+
+	kept := make([]slog.Attr, 0, len(attrs)-n)
+	for _, a := range attrs {
+		if r, ok := a.Value.Any().(retentionHint); ok {
+			hint = string(r)
+		} else {
+			kept = append(kept, a)
+		}
+	}
+	return kept, hint
+}
+
+// retentionRecord removes the retention hint attributes from the record
+// and returns it with the last hint, or hint if the record has none.
+func retentionRecord(record slog.Record, hint string) (slog.Record, string) {
+	found := false
+	record.Attrs(func(a slog.Attr) bool {
+		_, found = a.Value.Any().(retentionHint)
+		return !found
+	})
+	if !found {
+		return record, hint
+	}
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs, hint = splitRetention(attrs, hint)
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(attrs...)
+	return out, hint
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// startRuntimeStats logs a debug record with the number of goroutines and
+// basic memory statistics through logger every interval, until the returned
+// periodic is closed.
+func startRuntimeStats(logger Logger, interval time.Duration) *periodic {
+	return startPeriodic(interval, func() {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		logger.LogAttrs(internalContext(), slog.LevelDebug, "runtime stats",
+			slog.String(logTypeKey, "runtime"),
+			slog.Int("goroutines", runtime.NumGoroutine()),
+			slog.Uint64("heap_alloc", m.HeapAlloc),
+			slog.Uint64("sys", m.Sys),
+			slog.Uint64("num_gc", uint64(m.NumGC)),
+		)
+	})
+}
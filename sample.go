@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// sampler decides which records are kept. Records at warn level and above
+// are always kept.
+type sampler struct {
+	rate         float64 // fraction kept; 1 keeps all
+	traceAware   bool
+	traceSampled func(ctx context.Context) (sampled, ok bool)
+	limiter      *tokenBucket
+}
+
+// newSampler returns a sampler for the configuration, or nil if records are
+// never dropped.
+func newSampler(config Config) *sampler {
+	sampling := config.SampleRate > 0 && config.SampleRate < 1
+	limiting := config.RateLimit > 0
+	if !sampling && !limiting {
+		return nil
+	}
+	s := &sampler{
+		rate:         1,
+		traceAware:   config.SampleByTrace,
+		traceSampled: config.TraceSampled,
+	}
+	if sampling {
+		s.rate = config.SampleRate
+	}
+	if limiting {
+		s.limiter = newTokenBucket(config.RateLimit, config.RateBurst)
+	}
+	if s.traceSampled == nil {
+		s.traceSampled = sentryTraceSampled
+	}
+	return s
+}
+
+// keep reports whether the record should be emitted: it must be kept by
+// random sampling, then get a token from the rate limiter, unless its
+// context is marked with ForceTrace. The trace of a done context is not
+// consulted, so SampleRate applies to its records.
+func (s *sampler) keep(ctx context.Context, level slog.Level) bool {
+	if s == nil || level >= slog.LevelWarn {
+		return true
+	}
+	if ctx != nil && (isInternal(ctx) || traceForced(ctx)) {
+		return true
+	}
+	if s.traceAware && ctx != nil && !contextDone(ctx) {
+		if sampled, ok := s.traceSampled(ctx); ok && sampled {
+			return true
+		}
+	}
+	if s.rate < 1 && rand.Float64() >= s.rate {
+		return false
+	}
+	return s.limiter.take()
+}
+
+type ctxForceTraceKey struct{}
+
+// ForceTrace returns a copy of ctx marking the request it belongs to as
+// fully sampled, to debug a specific request in production: records logged
+// with the context, or one derived from it, bypass SampleRate and
+// RateLimit, and the spans Observe starts with it are sampled whatever the
+// Sentry traces sample rate. Other requests are sampled as configured.
+func ForceTrace(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxForceTraceKey{}, true)
+}
+
+// traceForced reports whether ctx is marked with ForceTrace.
+func traceForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(ctxForceTraceKey{}).(bool)
+	return forced
+}
+
+// sentryTraceSampled reports the sampling decision of the Sentry span in ctx,
+// or of the trace extracted into it with ExtractContext.
+func sentryTraceSampled(ctx context.Context) (sampled, ok bool) {
+	span := sentry.SpanFromContext(ctx)
+	if span == nil {
+		_, _, _, sampled, ok := remoteTraceFromContext(ctx)
+		return sampled, ok
+	}
+	return span.Sampled == sentry.SampledTrue, true
+}
+
+// tokenBucket is a rate limiter allowing bursts. It holds up to burst
+// tokens, refilled at rate tokens per second; each record kept takes one.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newTokenBucket returns a full bucket. A burst below 1 defaults to one
+// second's worth of tokens, and at least one.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst < 1 {
+		b = max(math.Ceil(rate), 1)
+	}
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: time.Now(), now: time.Now}
+}
+
+// take reports whether a token was available, and takes it if so. A nil
+// bucket always has tokens.
+func (b *tokenBucket) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.burst)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
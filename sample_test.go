@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	const rate, burst = 4, 3
+	clock := time.Unix(1700000000, 0)
+	b := newTokenBucket(rate, burst)
+	b.now = func() time.Time { return clock }
+	b.last = clock
+
+	for i := 0; i < burst; i++ {
+		if !b.take() {
+			t.Fatalf("take %d failed within the burst", i)
+		}
+	}
+	if b.take() {
+		t.Fatal("take succeeded with the bucket empty")
+	}
+
+	clock = clock.Add(time.Second / rate)
+	if !b.take() {
+		t.Fatal("take failed after one token was refilled")
+	}
+	if b.take() {
+		t.Fatal("take succeeded, although only one token was refilled")
+	}
+
+	// The bucket never holds more than burst tokens.
+	clock = clock.Add(time.Hour)
+	for i := 0; i < burst; i++ {
+		if !b.take() {
+			t.Fatalf("take %d failed after a full refill", i)
+		}
+	}
+	if b.take() {
+		t.Fatal("bucket refilled beyond its burst")
+	}
+}
+
+func TestTokenBucketDefaultBurst(t *testing.T) {
+	tests := []struct {
+		rate  float64
+		burst int
+		want  float64
+	}{
+		{rate: 10, burst: 0, want: 10},
+		{rate: 2.5, burst: -1, want: 3},
+		{rate: 0.2, burst: 0, want: 1},
+		{rate: 10, burst: 4, want: 4},
+	}
+	for _, tt := range tests {
+		b := newTokenBucket(tt.rate, tt.burst)
+		if b.burst != tt.want || b.tokens != tt.want {
+			t.Errorf("newTokenBucket(%v, %d): burst %v, tokens %v, want %v",
+				tt.rate, tt.burst, b.burst, b.tokens, tt.want)
+		}
+	}
+}
+
+func TestTokenBucketNil(t *testing.T) {
+	var b *tokenBucket
+	if !b.take() {
+		t.Error("nil bucket refused a token")
+	}
+}
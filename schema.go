@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	// logTypeKey is the reserved attribute distinguishing records emitted by
+	// the package itself from application records.
+	logTypeKey = "log_type"
+	// schemaVersion is bumped whenever the descriptor format changes.
+	schemaVersion = 1
+)
+
+// schemaField describes one field present in log records.
+type schemaField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// schemaFields lists the fields the configuration adds to records, besides
+// those passed by the application.
+func schemaFields(config Config) []schemaField {
+	gcp := outputFormat(config) == "gcp"
+	fields := []schemaField{{Name: slog.TimeKey, Type: "time"}}
+	if gcp {
+		fields = append(fields,
+			schemaField{Name: gcpSeverityKey, Type: "string"},
+			schemaField{Name: gcpMessageKey, Type: "string"},
+		)
+	} else {
+		fields = append(fields,
+			schemaField{Name: slog.LevelKey, Type: "string"},
+			schemaField{Name: slog.MessageKey, Type: "string"},
+		)
+		if scheme, err := newSeverityScheme(config.SeverityScheme); err == nil && scheme != nil {
+			fields = append(fields, schemaField{Name: scheme.key, Type: scheme.typ})
+		}
+	}
+	if config.IncludeLocalTime {
+		fields = append(fields, schemaField{Name: localTimeKey, Type: "string"})
+	}
+	if config.MessageOnly {
+		return fields
+	}
+	if gcp {
+		fields = append(fields,
+			schemaField{Name: gcpSourceLocationKey, Type: "object"},
+			schemaField{Name: gcpTraceKey, Type: "string", Optional: true},
+			schemaField{Name: gcpSpanIDKey, Type: "string", Optional: true},
+			schemaField{Name: gcpTraceSampledKey, Type: "boolean", Optional: true},
+		)
+	} else {
+		fields = append(fields, schemaField{Name: slog.SourceKey, Type: "object"})
+	}
+	fields = append(fields, schemaField{Name: logTypeKey, Type: "string", Optional: true})
+	if config.IncludeGitInfo {
+		fields = append(fields,
+			schemaField{Name: "git_commit", Type: "string", Optional: true},
+			schemaField{Name: "git_branch", Type: "string", Optional: true},
+		)
+	}
+	if config.IncludeProcessIdentity {
+		fields = append(fields,
+			schemaField{Name: "os_user", Type: "string", Optional: true},
+			schemaField{Name: "euid", Type: "integer", Optional: true},
+		)
+	}
+	if config.IncludeSize && sizeWriterFor(nil, config) != nil {
+		fields = append(fields, schemaField{Name: sizeKey, Type: "integer"})
+	}
+	if config.IntegrityChain {
+		fields = append(fields, schemaField{Name: chainKey, Type: "string"})
+	}
+	if config.IncludeSequence {
+		fields = append(fields, schemaField{Name: sequenceKey, Type: "integer"})
+	}
+	if config.IncludeRecordID {
+		fields = append(fields, schemaField{Name: recordIDKey, Type: "string"},
+			schemaField{Name: causedByKey, Type: "array", Optional: true})
+	}
+	if config.DefaultRetention > 0 {
+		fields = append(fields, schemaField{Name: retentionKey, Type: "string"})
+	}
+	if len(config.EscalationRules) > 0 {
+		fields = append(fields, schemaField{Name: escalatedFromKey, Type: "string", Optional: true})
+	}
+	if config.MaxAttrs > 0 {
+		fields = append(fields, schemaField{Name: droppedAttrsKey, Type: "integer", Optional: true})
+	}
+	return fields
+}
+
+// emitSchema writes the schema descriptor record directly to every output
+// handler, bypassing level filtering and sampling so that it is always
+// present.
+func emitSchema(handler slog.Handler, config Config) error {
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "log schema", 0)
+	record.AddAttrs(
+		slog.String(logTypeKey, "schema"),
+		slog.Int("schema_version", schemaVersion),
+		slog.Any("fields", schemaFields(config)),
+	)
+
+	if m, ok := handler.(*multiHandler); ok {
+		for _, h := range m.handlers {
+			if err := h.Handle(context.Background(), record.Clone()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return handler.Handle(context.Background(), record)
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// maxSentryRoutes bounds the Sentry projects records can be routed to, since
+// each has a client with its own transport, queue and goroutine.
+const maxSentryRoutes = 32
+
+// sentryRouter selects the Sentry client of a record from the value of one
+// of its attributes, such as a tenant ID.
+type sentryRouter struct {
+	key     string
+	clients map[string]*sentry.Client
+}
+
+// newSentryRouter returns a router sending the records whose key attribute
+// has a value of routes to the DSN it maps to, or nil if no routes are
+// configured. The clients share the other options of the default one.
+func newSentryRouter(key string, routes map[string]string, options sentry.ClientOptions) (*sentryRouter, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+	if err := validateSentryRoutes(key, routes); err != nil {
+		return nil, err
+	}
+
+	r := &sentryRouter{key: key, clients: make(map[string]*sentry.Client, len(routes))}
+	values := make([]string, 0, len(routes))
+	for v := range routes {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	for _, v := range values {
+		options.Dsn = routes[v]
+		client, err := sentry.NewClient(options)
+		if err != nil {
+			return nil, fmt.Errorf("sentry client for %s %q: %s", key, v, err)
+		}
+		r.clients[v] = client
+	}
+	return r, nil
+}
+
+// validateSentryRoutes checks the routing configuration, before the
+// clients are created.
+func validateSentryRoutes(key string, routes map[string]string) error {
+	if len(routes) == 0 {
+		return nil
+	}
+	if key == "" {
+		return fmt.Errorf("sentry routes require SentryRouteAttr")
+	}
+	if len(routes) > maxSentryRoutes {
+		return fmt.Errorf("too many Sentry routes: %d, at most %d allowed", len(routes), maxSentryRoutes)
+	}
+	return nil
+}
+
+// client returns the client of the route for value, or nil if it has none.
+func (r *sentryRouter) client(value any) *sentry.Client {
+	if r == nil || value == nil {
+		return nil
+	}
+	return r.clients[fmt.Sprint(value)]
+}
+
+// Close flushes the events queued by every route.
+func (r *sentryRouter) Close() error {
+	for _, client := range r.clients {
+		client.Flush(2 * time.Second)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// sentryTagKey is the key of the attributes created by SentryTag.
+const sentryTagKey = "sentry_tag"
+
+// sentryTag is the value of an attribute that sets a Sentry tag instead of
+// being logged.
+type sentryTag struct {
+	key, value string
+}
+
+type ctxSentryTagsKey struct{}
+
+// SentryTag returns an attribute that sets a tag on the Sentry event of the
+// record it is logged with. The attribute is not written to the log output.
+func SentryTag(key, value string) slog.Attr {
+	return slog.Any(sentryTagKey, sentryTag{key: key, value: value})
+}
+
+// WithSentryTag returns a logger that sets a tag on the Sentry events it
+// captures, and on those of loggers derived from it. The tag is not added to
+// the global scope, and it is not written to the log output. Calls can be
+// chained:
+//
+//	l = logger.WithSentryTag(logger.WithSentryTag(l, "region", "us-east"), "tier", "gold")
+func WithSentryTag(l Logger, key, value string) Logger {
+	return l.With(SentryTag(key, value))
+}
+
+// splitSentryTags removes the Sentry tag attributes from attrs and adds them
+// to a copy of tags. If attrs has none, both are returned unchanged.
+func splitSentryTags(attrs []slog.Attr, tags map[string]string) ([]slog.Attr, map[string]string) {
+	n := 0
+	for _, a := range attrs {
+		if _, ok := a.Value.Any().(sentryTag); ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return attrs, tags
+	}
+
+	merged := make(map[string]string, len(tags)+n)
+	for k, v := range tags {
+		merged[k] = v
+	}
+	kept := make([]slog.Attr, 0, len(attrs)-n)
+	for _, a := range attrs {
+		if t, ok := a.Value.Any().(sentryTag); ok {
+			merged[t.key] = t.value
+		} else {
+			kept = append(kept, a)
+		}
+	}
+	return kept, merged
+}
+
+// sentryTagsRecord removes the Sentry tag attributes from the record and
+// merges them over tags. If the record has none, both are returned unchanged.
+func sentryTagsRecord(record slog.Record, tags map[string]string) (slog.Record, map[string]string) {
+	found := false
+	record.Attrs(func(a slog.Attr) bool {
+		_, found = a.Value.Any().(sentryTag)
+		return !found
+	})
+	if !found {
+		return record, tags
+	}
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs, tags = splitSentryTags(attrs, tags)
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(attrs...)
+	return out, tags
+}
+
+// withSentryTags returns a context carrying the Sentry tags of a record.
+func withSentryTags(ctx context.Context, tags map[string]string) context.Context {
+	if len(tags) == 0 {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxSentryTagsKey{}, tags)
+}
+
+func sentryTagsFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(ctxSentryTagsKey{}).(map[string]string)
+	return tags
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// severityScheme maps levels to the severity written next to the level.
+type severityScheme struct {
+	key     string
+	typ     string // schema field type
+	mapping func(level slog.Level) slog.Value
+}
+
+// severitySchemes are the values of Config.SeverityScheme.
+var severitySchemes = map[string]severityScheme{
+	"syslog": {key: "severity", typ: "integer", mapping: syslogSeverity},
+	"otel":   {key: "severity_number", typ: "integer", mapping: otelSeverity},
+	"gcp":    {key: "severity", typ: "string", mapping: gcpSeverity},
+}
+
+// newSeverityScheme returns the scheme named name, or nil if name is empty.
+func newSeverityScheme(name string) (*severityScheme, error) {
+	if name == "" {
+		return nil, nil
+	}
+	scheme, ok := severitySchemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported severity scheme %q", name)
+	}
+	return &scheme, nil
+}
+
+// replacer returns a ReplaceAttr function adding the severity next to the
+// built-in level, by replacing the level attribute with an inlined group.
+// The level is written under its name, so that the group members do not
+// match again when ReplaceAttr is applied to them.
+func (s *severityScheme) replacer() replaceAttrFunc {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) > 0 || a.Key != slog.LevelKey {
+			return a
+		}
+		level, ok := a.Value.Any().(slog.Level)
+		if !ok {
+			return a
+		}
+		return slog.Attr{Value: slog.GroupValue(
+			slog.String(slog.LevelKey, levelName(level)),
+			slog.Attr{Key: s.key, Value: s.mapping(level)},
+		)}
+	}
+}
+
+// syslogSeverity maps a level to an RFC 5424 severity: 7 debug, 6
+// informational, 4 warning, 3 error and 2 critical.
+func syslogSeverity(level slog.Level) slog.Value {
+	switch {
+	case level >= LevelFatal:
+		return slog.IntValue(2)
+	case level >= slog.LevelError:
+		return slog.IntValue(3)
+	case level >= slog.LevelWarn:
+		return slog.IntValue(4)
+	case level >= slog.LevelInfo:
+		return slog.IntValue(6)
+	default:
+		return slog.IntValue(7)
+	}
+}
+
+// otelSeverity maps a level to an OpenTelemetry severity number, which
+// slog levels are designed to match with an offset of 9: DEBUG is 5, INFO
+// 9, WARN 13, ERROR 17 and FATAL 21. Levels in between map to the numbers
+// in between, clamped to the valid range of 1 to 24.
+func otelSeverity(level slog.Level) slog.Value {
+	return slog.IntValue(min(max(int(level)+9, 1), 24))
+}
+
+// gcpSeverity maps a level to a Google Cloud Logging severity name.
+func gcpSeverity(level slog.Level) slog.Value {
+	switch {
+	case level >= LevelFatal:
+		return slog.StringValue("CRITICAL")
+	case level >= slog.LevelError:
+		return slog.StringValue("ERROR")
+	case level >= slog.LevelWarn:
+		return slog.StringValue("WARNING")
+	case level >= slog.LevelInfo:
+		return slog.StringValue("INFO")
+	default:
+		return slog.StringValue("DEBUG")
+	}
+}
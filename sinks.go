@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// SinkID identifies a sink attached with AddSink.
+type SinkID uint64
+
+var (
+	errNotRootLogger = errors.New("logger was not created by New")
+	errUnknownSink   = errors.New("unknown sink")
+)
+
+// sink is a handler attached with AddSink.
+type sink struct {
+	id      SinkID
+	handler slog.Handler
+}
+
+// sinkSet holds the sinks of a logger and the loggers derived from it. The
+// list is replaced on each change, so records read it without locking; the
+// lock is held for reading while records are handed to sinks, so that a
+// removed sink is no longer in use once RemoveSink closes it.
+type sinkSet struct {
+	mu     sync.RWMutex
+	sinks  atomic.Pointer[[]sink]
+	nextID SinkID
+}
+
+// sinkCache holds the sink handlers of a root handler, derived with its
+// attributes and groups, and with those before its first group for records
+// carrying top-level fields, for one version of the sink list.
+type sinkCache struct {
+	sinks    *[]sink
+	handlers []slog.Handler
+	top      []slog.Handler
+}
+
+// AddSink attaches a handler to l, and to the loggers derived from it
+// before or after, until it is removed with RemoveSink or l is closed. The
+// handler receives every record the logger emits at a level it is enabled
+// for, as passed to the configured outputs: sampled, enriched and redacted,
+// with the attributes and groups added with With and WithGroup. Records of
+// lower levels than the logger's are emitted for it if it is enabled for
+// them, without reaching the other outputs.
+//
+// AddSink is safe to call concurrently with logging. It fails if l was not
+// created by New.
+func AddSink(l Logger, handler slog.Handler) (SinkID, error) {
+	h, ok := l.Handler().(*rootHandler)
+	if !ok {
+		return 0, errNotRootLogger
+	}
+	s := h.sinks
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	var sinks []sink
+	if old := s.sinks.Load(); old != nil {
+		sinks = append(sinks, *old...)
+	}
+	sinks = append(sinks, sink{id: s.nextID, handler: handler})
+	s.sinks.Store(&sinks)
+	return s.nextID, nil
+}
+
+// RemoveSink detaches the sink from l and the loggers sharing its sinks,
+// waits for the records being handed to it, and closes it if it implements
+// io.Closer, so buffered records are flushed. No record reaches the sink
+// once RemoveSink returns.
+func RemoveSink(l Logger, id SinkID) error {
+	h, ok := l.Handler().(*rootHandler)
+	if !ok {
+		return errNotRootLogger
+	}
+	s := h.sinks
+	s.mu.Lock()
+	var removed slog.Handler
+	var sinks []sink
+	if old := s.sinks.Load(); old != nil {
+		for _, sk := range *old {
+			if sk.id == id {
+				removed = sk.handler
+			} else {
+				sinks = append(sinks, sk)
+			}
+		}
+	}
+	if removed != nil && len(sinks) == 0 {
+		s.sinks.Store(nil)
+	} else if removed != nil {
+		s.sinks.Store(&sinks)
+	}
+	s.mu.Unlock()
+
+	if removed == nil {
+		return errUnknownSink
+	}
+	if c, ok := removed.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Close detaches and closes every sink, when the logger is closed.
+func (s *sinkSet) Close() error {
+	s.mu.Lock()
+	old := s.sinks.Swap(nil)
+	s.mu.Unlock()
+	if old == nil {
+		return nil
+	}
+	var errs []error
+	for _, sk := range *old {
+		if c, ok := sk.handler.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sinksEnabled reports whether any sink is enabled for the level.
+func (h *rootHandler) sinksEnabled(ctx context.Context, level slog.Level) bool {
+	sinks := h.sinks.sinks.Load()
+	if sinks == nil {
+		return false
+	}
+	for _, sk := range *sinks {
+		if sk.handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSinks passes the record to every sink enabled for its level, and
+// returns the errors they report joined together. If top is set, the
+// record was ungrouped for h.top, and is passed to the sinks derived like
+// it.
+func (h *rootHandler) handleSinks(ctx context.Context, record slog.Record, top bool) error {
+	if sinks := h.sinks.sinks.Load(); sinks == nil || len(*sinks) == 0 {
+		return nil
+	}
+	h.sinks.mu.RLock()
+	defer h.sinks.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range h.sinkHandlers(top) {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sinkHandlers returns the sink handlers with the attributes and groups of
+// h, or with those before its first group if top is set, deriving them
+// again when the sinks changed.
+func (h *rootHandler) sinkHandlers(top bool) []slog.Handler {
+	sinks := h.sinks.sinks.Load()
+	if sinks == nil {
+		return nil
+	}
+	c := h.sinkCache.Load()
+	if c == nil || c.sinks != sinks {
+		c = &sinkCache{sinks: sinks, handlers: deriveSinks(*sinks, h.goas)}
+		if h.top != nil {
+			c.top = deriveSinks(*sinks, h.goas[:h.topGoas])
+		}
+		h.sinkCache.Store(c)
+	}
+	if top {
+		return c.top
+	}
+	return c.handlers
+}
+
+// deriveSinks returns the handlers of the sinks with the groups and attributes
+// of goas added.
+func deriveSinks(sinks []sink, goas []groupOrAttrs) []slog.Handler {
+	handlers := make([]slog.Handler, len(sinks))
+	for i, sk := range sinks {
+		handler := sk.handler
+		for _, goa := range goas {
+			if goa.group != "" {
+				handler = handler.WithGroup(goa.group)
+			} else {
+				handler = handler.WithAttrs(goa.attrs)
+			}
+		}
+		handlers[i] = handler
+	}
+	return handlers
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// closingHandler is a sink that counts the calls to its Close method.
+type closingHandler struct {
+	slog.Handler
+	closed int
+}
+
+func (h *closingHandler) Close() error {
+	h.closed++
+	return nil
+}
+
+func TestSinks(t *testing.T) {
+	var out, first, second bytes.Buffer
+	l, err := New(Config{Format: "json", Output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := l.With("derived", "before")
+
+	h1 := &closingHandler{Handler: slog.NewJSONHandler(&first, &slog.HandlerOptions{Level: slog.LevelDebug})}
+	id1, err := AddSink(l, h1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2 := &closingHandler{Handler: slog.NewJSONHandler(&second, nil)}
+	if _, err := AddSink(l, h2); err != nil {
+		t.Fatal(err)
+	}
+
+	before.Info("one")
+	l.Debug("below the logger's level")
+	if err := RemoveSink(l, id1); err != nil {
+		t.Fatal(err)
+	}
+	if h1.closed != 1 {
+		t.Errorf("removed sink closed %d times, want 1", h1.closed)
+	}
+	l.WithGroup("g").Info("two")
+
+	if got := strings.Count(first.String(), "\n"); got != 2 {
+		t.Errorf("removed sink got %d records, want 2:\n%s", got, first.String())
+	}
+	if !strings.Contains(first.String(), `"derived":"before"`) || !strings.Contains(first.String(), "below the logger's level") {
+		t.Errorf("sink missed a record:\n%s", first.String())
+	}
+	if got := strings.Count(second.String(), "\n"); got != 2 {
+		t.Errorf("remaining sink got %d records, want 2:\n%s", got, second.String())
+	}
+	if strings.Contains(out.String(), "below the logger's level") {
+		t.Errorf("record below the logger's level reached the output:\n%s", out.String())
+	}
+
+	if err := RemoveSink(l, id1); !errors.Is(err, errUnknownSink) {
+		t.Errorf("removing a sink twice: %v, want %v", err, errUnknownSink)
+	}
+	if err := Close(l); err != nil {
+		t.Fatal(err)
+	}
+	if h1.closed != 1 || h2.closed != 1 {
+		t.Errorf("sinks closed %d and %d times, want once each", h1.closed, h2.closed)
+	}
+}
+
+func TestSinksNotRootLogger(t *testing.T) {
+	l := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	if _, err := AddSink(l, slog.NewJSONHandler(io.Discard, nil)); !errors.Is(err, errNotRootLogger) {
+		t.Errorf("AddSink: %v, want %v", err, errNotRootLogger)
+	}
+	if err := RemoveSink(l, 1); !errors.Is(err, errNotRootLogger) {
+		t.Errorf("RemoveSink: %v, want %v", err, errNotRootLogger)
+	}
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// sizeKey is the field holding the size of a record.
+const sizeKey = "size"
+
+// sizeWriter adds the size in bytes of each record to it, as encoded by the
+// output handler, which writes every record in a single call. Records are
+// patched rather than encoded twice, so the size does not count the size
+// field itself.
+type sizeWriter struct {
+	w    io.Writer
+	json bool // records are JSON objects rather than key=value lines
+}
+
+// newSizeWriter returns a sizeWriter for the format, or nil if records in
+// that format cannot be patched.
+func newSizeWriter(w io.Writer, format string) *sizeWriter {
+	switch format {
+	case "", "json", "gcp":
+		return &sizeWriter{w: w, json: true}
+	case "text":
+		return &sizeWriter{w: w}
+	default:
+		return nil
+	}
+}
+
+// sizeWriterFor returns a sizeWriter for the output of the configuration,
+// or nil if its records cannot be patched. Indented text records span
+// several lines, so they are not.
+func sizeWriterFor(w io.Writer, config Config) *sizeWriter {
+	format := outputFormat(config)
+	if format == "text" && config.TextIndent > 0 {
+		return nil
+	}
+	return newSizeWriter(w, format)
+}
+
+// Write writes the record p with its size added as the last field.
+func (s *sizeWriter) Write(p []byte) (int, error) {
+	line, ok := bytes.CutSuffix(p, []byte("\n"))
+	if !ok || (s.json && !bytes.HasSuffix(line, []byte("}"))) {
+		return s.w.Write(p)
+	}
+
+	buf := appendField(make([]byte, 0, len(p)+len(sizeKey)+16), line, s.json, sizeKey, strconv.Itoa(len(p)))
+	if _, err := s.w.Write(append(buf, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendField appends the record line, without its newline, to buf with a
+// field added last: a JSON member if the record is a JSON object, or else
+// a key=value pair. value must already be encoded for the format.
+func appendField(buf, line []byte, json bool, key, value string) []byte {
+	if json {
+		buf = append(buf, line[:len(line)-1]...)
+		return append(buf, `,"`+key+`":`+value+"}"...)
+	}
+	buf = append(buf, line...)
+	return append(buf, " "+key+"="+value...)
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// QueryLogOptions configures a QueryLogger.
+type QueryLogOptions struct {
+	// SlowThreshold is the duration from which queries are logged as slow,
+	// at warn level. Zero disables slow query detection.
+	SlowThreshold time.Duration
+	// OnlySlow logs slow and failed queries only.
+	OnlySlow bool
+	// LogArgs logs the values of bound parameters in an "args" attribute.
+	// By default only their number is logged, as "arg_count", since they
+	// often carry personal data.
+	LogArgs bool
+}
+
+// QueryLogger logs database queries with their timing. Queries are logged
+// by their template, with bound parameters left out unless enabled by
+// QueryLogOptions.LogArgs.
+type QueryLogger struct {
+	logger Logger
+	opts   QueryLogOptions
+}
+
+// NewQueryLogger returns a QueryLogger logging through l.
+func NewQueryLogger(l Logger, opts QueryLogOptions) *QueryLogger {
+	return &QueryLogger{logger: l, opts: opts}
+}
+
+// Start starts timing a query and returns the function to call with its
+// error, or nil, once it completes:
+//
+//	done := ql.Start(ctx, query, id)
+//	rows, err := db.QueryContext(ctx, query, id)
+//	done(err)
+//
+// Completed queries are logged at debug level as "query", slow ones at warn
+// level as "slow query" and failed ones at error level as "query failed",
+// with "query", "duration" and, on failure, "error" attributes. The record
+// reports the caller of the returned function as its source.
+func (q *QueryLogger) Start(ctx context.Context, query string, args ...any) func(err error) {
+	t := StartTimer(q.logger)
+	return func(err error) {
+		d := t.Elapsed()
+		slow := q.opts.SlowThreshold > 0 && d >= q.opts.SlowThreshold
+
+		level, msg := slog.LevelDebug, "query"
+		switch {
+		case err != nil:
+			level, msg = slog.LevelError, "query failed"
+		case slow:
+			level, msg = slog.LevelWarn, "slow query"
+		case q.opts.OnlySlow:
+			return
+		}
+
+		attrs := []slog.Attr{slog.String("query", query)}
+		if q.opts.LogArgs {
+			attrs = append(attrs, slog.Any("args", args))
+		} else {
+			attrs = append(attrs, slog.Int("arg_count", len(args)))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		}
+		t.log(ctx, 1, level, msg, d, attrs)
+	}
+}
@@ -0,0 +1,65 @@
+package logger
+
+import "github.com/getsentry/sentry-go"
+
+// defaultStackTraceDepth is the number of frames kept when
+// Config.StackTraceDepth is not set.
+const defaultStackTraceDepth = 32
+
+// internalModules are the packages whose frames are removed from stack
+// traces, since they only show the logging machinery.
+var internalModules = map[string]bool{
+	"log/slog":                     true,
+	"github.com/stratastor/logger": true,
+}
+
+// stackTraceTrimmer returns a Sentry BeforeSend hook limiting the stack
+// traces of events to depth frames. When frames are dropped, the event gets
+// a "stacktrace_truncated" extra with the number of frames dropped.
+func stackTraceTrimmer(depth int) func(*sentry.Event, *sentry.EventHint) *sentry.Event {
+	if depth <= 0 {
+		depth = defaultStackTraceDepth
+	}
+	return func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		dropped := 0
+		for i := range event.Threads {
+			dropped += trimStacktrace(event.Threads[i].Stacktrace, depth)
+		}
+		for i := range event.Exception {
+			dropped += trimStacktrace(event.Exception[i].Stacktrace, depth)
+		}
+		if dropped > 0 {
+			if event.Extra == nil {
+				event.Extra = map[string]interface{}{}
+			}
+			event.Extra["stacktrace_truncated"] = dropped
+		}
+		return event
+	}
+}
+
+// trimStacktrace removes the logging machinery frames from st and keeps at
+// most depth of the remaining frames. Sentry orders frames from the
+// outermost call to the innermost, so the innermost frames, closest to where
+// the record was logged, are kept. It returns the number of frames dropped
+// by the depth limit.
+func trimStacktrace(st *sentry.Stacktrace, depth int) int {
+	if st == nil {
+		return 0
+	}
+
+	frames := st.Frames[:0]
+	for _, f := range st.Frames {
+		if !internalModules[f.Module] {
+			frames = append(frames, f)
+		}
+	}
+
+	dropped := 0
+	if len(frames) > depth {
+		dropped = len(frames) - depth
+		frames = frames[dropped:]
+	}
+	st.Frames = frames
+	return dropped
+}
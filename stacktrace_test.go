@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestStackTraceTrimmer(t *testing.T) {
+	const depth = 5
+
+	// Frames run from the outermost call to the innermost: 10 application
+	// frames interleaved with logging machinery frames, the latter at the
+	// innermost end as when a record is captured.
+	var frames []sentry.Frame
+	for i := 0; i < 10; i++ {
+		frames = append(frames, sentry.Frame{Module: "example.com/app", Function: fmt.Sprintf("f%d", i)})
+		if i%3 == 0 {
+			frames = append(frames, sentry.Frame{Module: "log/slog", Function: "(*Logger).log"})
+		}
+	}
+	frames = append(frames,
+		sentry.Frame{Module: "github.com/stratastor/logger", Function: "(*rootHandler).Handle"},
+		sentry.Frame{Module: "github.com/stratastor/logger", Function: "(*sentryHandler).capture"},
+	)
+	event := &sentry.Event{
+		Threads: []sentry.Thread{{Stacktrace: &sentry.Stacktrace{Frames: frames}}},
+	}
+
+	event = stackTraceTrimmer(depth)(event, nil)
+
+	got := event.Threads[0].Stacktrace.Frames
+	if len(got) != depth {
+		t.Fatalf("kept %d frames, want %d", len(got), depth)
+	}
+	for i, f := range got {
+		if internalModules[f.Module] {
+			t.Errorf("frame %d: internal frame %s.%s kept", i, f.Module, f.Function)
+		}
+		if want := fmt.Sprintf("f%d", 10-depth+i); f.Function != want {
+			t.Errorf("frame %d is %s, want %s", i, f.Function, want)
+		}
+	}
+	if got, want := event.Extra["stacktrace_truncated"], 10-depth; got != want {
+		t.Errorf("stacktrace_truncated = %v, want %d", got, want)
+	}
+}
+
+func TestStackTraceTrimmerWithinDepth(t *testing.T) {
+	frames := []sentry.Frame{
+		{Module: "example.com/app", Function: "main"},
+		{Module: "example.com/app", Function: "handle"},
+		{Module: "log/slog", Function: "(*Logger).Error"},
+	}
+	event := &sentry.Event{
+		Exception: []sentry.Exception{{Stacktrace: &sentry.Stacktrace{Frames: frames}}},
+	}
+
+	event = stackTraceTrimmer(5)(event, nil)
+
+	if got := event.Exception[0].Stacktrace.Frames; len(got) != 2 {
+		t.Errorf("kept %d frames, want the 2 application frames", len(got))
+	}
+	if _, ok := event.Extra["stacktrace_truncated"]; ok {
+		t.Errorf("stacktrace_truncated set, although no frame was dropped by depth")
+	}
+}
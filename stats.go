@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// sequenceKey is the attribute holding the sequence number of a record.
+const sequenceKey = "seq"
+
+// stats counts the records emitted by a logger and its derivatives. The
+// count after a record is emitted is its sequence number.
+type stats struct {
+	start   time.Time
+	records atomic.Int64
+	levels  [4]atomic.Int64 // records emitted, by levelIndex
+	dropped atomic.Int64    // records sampled out or rate limited
+}
+
+func newStats() *stats {
+	return &stats{start: time.Now()}
+}
+
+// levelIndex returns the index in stats.levels of the counts of level:
+// debug, info, warn and error, levels counting with the highest below them.
+func levelIndex(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 0
+	case level < slog.LevelWarn:
+		return 1
+	case level < slog.LevelError:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// uptime returns the time elapsed since the logger was created.
+func (s *stats) uptime() time.Duration {
+	return time.Since(s.start)
+}
@@ -0,0 +1,25 @@
+package logger
+
+import "log/slog"
+
+// logSummary logs the summary of the records emitted through logger since
+// it was created, not counting the summary itself, whatever the level of
+// the logger.
+func logSummary(logger Logger, st *stats) {
+	var levels [len(st.levels)]int64
+	for i := range st.levels {
+		levels[i] = st.levels[i].Load()
+	}
+	logInternal(logger, slog.LevelInfo, "log summary",
+		slog.String(logTypeKey, "summary"),
+		slog.Int64("records", st.records.Load()),
+		slog.Group("levels",
+			slog.Int64("debug", levels[0]),
+			slog.Int64("info", levels[1]),
+			slog.Int64("warn", levels[2]),
+			slog.Int64("error", levels[3]),
+		),
+		slog.Int64("dropped", st.dropped.Load()),
+		slog.Duration("uptime", st.uptime()),
+	)
+}
@@ -0,0 +1,20 @@
+package logger
+
+import "log/slog"
+
+// Tee returns a logger that broadcasts every record to each of the given
+// loggers, which may be configured completely differently, for example one
+// writing JSON to a file and one writing text to the console.
+//
+// Each logger keeps its own level: a record is passed to the loggers enabled
+// for its level and skipped by the others, so the tee is enabled for a level
+// as soon as one of them is. Handler errors from all loggers are joined and
+// returned by the tee's handler. Closing the tee with Close closes every
+// logger.
+func Tee(loggers ...Logger) Logger {
+	handlers := make([]slog.Handler, len(loggers))
+	for i, l := range loggers {
+		handlers[i] = l.Handler()
+	}
+	return slog.New(&multiHandler{handlers: handlers})
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Timer measures how long an operation takes and logs it when stopped.
+type Timer struct {
+	logger Logger
+	start  time.Time
+}
+
+// StartTimer returns a timer started now, logging through l.
+//
+//	t := logger.StartTimer(l)
+//	rebuildIndex()
+//	t.Stop(ctx, slog.LevelInfo, "index rebuilt", "docs", n)
+func StartTimer(l Logger) *Timer {
+	return &Timer{logger: l, start: time.Now()}
+}
+
+// Elapsed returns the time elapsed since the timer was started.
+func (t *Timer) Elapsed() time.Duration {
+	return time.Since(t.start)
+}
+
+// Stop logs msg at level with the elapsed time in a "duration" attribute,
+// and returns the elapsed time. args are interpreted as in slog.Logger.Log.
+// The record reports the caller of Stop as its source.
+func (t *Timer) Stop(ctx context.Context, level slog.Level, msg string, args ...any) time.Duration {
+	d := t.Elapsed()
+	t.log(ctx, 1, level, msg, d, argsToAttrs(args))
+	return d
+}
+
+// log logs the record for a stopped timer, reporting as its source the
+// frame skip frames above the caller of log.
+func (t *Timer) log(ctx context.Context, skip int, level slog.Level, msg string, d time.Duration, attrs []slog.Attr) {
+	attrs = append(attrs, slog.Duration("duration", d))
+	WithCallerSkip(t.logger, skip+1).LogAttrs(ctx, level, msg, attrs...)
+}
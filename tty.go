@@ -0,0 +1,38 @@
+package logger
+
+import "os"
+
+// isTerminal reports whether f is a terminal, that is, a character device
+// such as a TTY rather than a file, pipe or socket.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// outputFormat returns the format records are written in. An explicit
+// Format wins; otherwise, with AutoFormat, records use the text format if
+// they are written to a terminal, stdout or an Output file, and JSON if they
+// are not.
+func outputFormat(config Config) string {
+	if config.Format != "" || !config.AutoFormat {
+		return config.Format
+	}
+	if config.FIFOPath != "" || os.Getenv("TERM") == "dumb" {
+		return "json"
+	}
+	out := os.Stdout
+	if config.Output != nil {
+		f, ok := config.Output.(*os.File)
+		if !ok {
+			return "json"
+		}
+		out = f
+	}
+	if isTerminal(out) {
+		return "text"
+	}
+	return "json"
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// rootKeyWriter wraps each JSON record in an object under a single key,
+// such as {"log":{...}}. The output handler writes every record in a single
+// call.
+type rootKeyWriter struct {
+	w      io.Writer
+	prefix []byte // {"key":
+}
+
+// newRootKeyWriter returns a rootKeyWriter for the format, or nil if its
+// records are not JSON objects that can be wrapped.
+func newRootKeyWriter(w io.Writer, format, key string) *rootKeyWriter {
+	if key == "" || (format != "" && format != "json") {
+		return nil
+	}
+	encoded, _ := json.Marshal(key)
+	return &rootKeyWriter{w: w, prefix: append(append([]byte("{"), encoded...), ':')}
+}
+
+// Write writes the record p wrapped under the key.
+func (r *rootKeyWriter) Write(p []byte) (int, error) {
+	line, ok := bytes.CutSuffix(p, []byte("\n"))
+	if !ok || !bytes.HasPrefix(line, []byte("{")) {
+		return r.w.Write(p)
+	}
+
+	buf := make([]byte, 0, len(r.prefix)+len(p)+1)
+	buf = append(buf, r.prefix...)
+	buf = append(buf, line...)
+	if _, err := r.w.Write(append(buf, "}\n"...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// unwrapRootKey returns the record wrapped in line by a rootKeyWriter, and
+// whether line is such an object, of a single member holding an object.
+func unwrapRootKey(line []byte) ([]byte, bool) {
+	if len(line) < 2 || line[0] != '{' || line[1] != '"' || line[len(line)-1] != '}' {
+		return nil, false
+	}
+	for i := 2; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			inner := line[i+1 : len(line)-1]
+			if !bytes.HasPrefix(inner, []byte(":{")) || !bytes.HasSuffix(inner, []byte("}")) {
+				return nil, false
+			}
+			return inner[1:], true
+		}
+	}
+	return nil, false
+}